@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/microsoft/notation-cose/internal/config"
+	"github.com/notaryproject/notation-go/crypto/cryptoutil"
+	"github.com/urfave/cli/v2"
+)
+
+// configFlag specifies the configuration file of the plugin.
+var configFlag = &cli.StringFlag{
+	Name:    "config",
+	Usage:   "path to the plugin configuration file, which defaults to $XDG_CONFIG_HOME/notation-cose/config.json",
+	EnvVars: []string{config.EnvConfigPath},
+}
+
+var configCommand = &cli.Command{
+	Name:  "config",
+	Usage: "Manage the plugin configuration",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "validate",
+			Usage:     "Validate the plugin configuration, and load its keys and trust stores",
+			ArgsUsage: "[<file>]",
+			Action:    runConfigValidate,
+		},
+	},
+}
+
+// loadConfig loads the configuration file specified by the flag, or the
+// configuration file at the default path if present.
+func loadConfig(ctx *cli.Context) (*config.Config, error) {
+	if path := ctx.String(configFlag.Name); path != "" {
+		return config.Load(path)
+	}
+	return config.LoadDefault()
+}
+
+func runConfigValidate(ctx *cli.Context) error {
+	// load config
+	path := ctx.Args().First()
+	if path == "" {
+		path = ctx.String(configFlag.Name)
+	}
+	if path == "" {
+		var err error
+		if path, err = config.DefaultPath(); err != nil {
+			return err
+		}
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+
+	// load keys and trust stores
+	for name := range cfg.Keys {
+		if _, err := loadSigningKey(cfg, name); err != nil {
+			return fmt.Errorf("key profile %q: %w", name, err)
+		}
+	}
+	for name, paths := range cfg.TrustStores {
+		for _, path := range paths {
+			if _, err := cryptoutil.ReadCertificateFile(path); err != nil {
+				return fmt.Errorf("trust store %q: %w", name, err)
+			}
+		}
+	}
+
+	fmt.Printf("%s: valid\n", path)
+	return nil
+}
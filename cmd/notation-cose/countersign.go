@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/microsoft/notation-cose/pkg/cose"
+	"github.com/notaryproject/notation-go"
+	"github.com/notaryproject/notation-go/crypto/cryptoutil"
+	"github.com/urfave/cli/v2"
+)
+
+var countersignCommand = &cli.Command{
+	Name:      "countersign",
+	Usage:     "Add a countersignature, such as an approval, to an existing COSE signature",
+	ArgsUsage: "<signature>",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "key",
+			Aliases:  []string{"k"},
+			Usage:    "signing key profile, key descriptor in JSON, or key in the format of <key_path>:<cert_path>[:<tsa_url>]",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "blob",
+			Usage: "path to the blob signed by the signature with detached payload",
+		},
+		&cli.DurationFlag{
+			Name:    "expiry",
+			Aliases: []string{"e"},
+			Usage:   "expire duration of the countersignature",
+		},
+		stapleRevocationFlag,
+		outputFlag,
+	},
+	Action: runCountersign,
+}
+
+func runCountersign(ctx *cli.Context) error {
+	// initialize
+	args := ctx.Args()
+	if args.Len() != 1 {
+		return errors.New("missing signature file")
+	}
+	sig, err := os.ReadFile(args.Get(0))
+	if err != nil {
+		return err
+	}
+	var opts notation.SignOptions
+	if expiry := ctx.Duration("expiry"); expiry != 0 {
+		opts.Expiry = time.Now().Add(expiry)
+	}
+
+	// countersign
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	signer, opts, err := getSignerWithOptions(cfg, ctx.String("key"), opts)
+	if err != nil {
+		return err
+	}
+	if ctx.Bool(stapleRevocationFlag.Name) {
+		signer.RevocationFetcher = &cose.HTTPFetcher{}
+	}
+	if path := ctx.String("blob"); path != "" {
+		var content []byte
+		if content, err = os.ReadFile(path); err == nil {
+			sig, err = signer.CountersignBlob(ctx.Context, sig, content, opts)
+		}
+	} else {
+		sig, err = signer.Countersign(ctx.Context, sig, opts)
+	}
+	if err != nil {
+		return err
+	}
+	return writeOutput(ctx, sig)
+}
+
+// countersignatureFlags configure the verifier to require and validate the
+// countersignatures.
+var countersignatureFlags = []cli.Flag{
+	&cli.StringSliceFlag{
+		Name:  "countersigner-cert",
+		Usage: "path to the certificates trusted for the countersignatures, which are validated if present",
+	},
+	&cli.BoolFlag{
+		Name:  "require-countersignature",
+		Usage: "require at least one trusted countersignature",
+	},
+}
+
+// setCountersignaturePolicy configures the verifier to validate the
+// countersignatures against the trusted certificates of the countersigners.
+func setCountersignaturePolicy(ctx *cli.Context, verifier *cose.Verifier) error {
+	paths := ctx.StringSlice("countersigner-cert")
+	if len(paths) == 0 {
+		if ctx.Bool("require-countersignature") {
+			return errors.New("countersigner-cert is required to require countersignatures")
+		}
+		return nil
+	}
+	roots := x509.NewCertPool()
+	for _, path := range paths {
+		certs, err := cryptoutil.ReadCertificateFile(path)
+		if err != nil {
+			return err
+		}
+		for _, cert := range certs {
+			roots.AddCert(cert)
+		}
+	}
+	verifier.Countersignature = &cose.CountersignaturePolicy{
+		Required: ctx.Bool("require-countersignature"),
+		VerifyOptions: x509.VerifyOptions{
+			Roots: roots,
+		},
+	}
+	return nil
+}
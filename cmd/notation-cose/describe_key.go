@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"fmt"
+
+	"github.com/microsoft/notation-cose/pkg/protocol"
+	"github.com/urfave/cli/v2"
+)
+
+var describeKeyCommand = pluginCommand(
+	protocol.CommandDescribeKey,
+	"Describe the signing key",
+	runDescribeKey,
+)
+
+func runDescribeKey(ctx *cli.Context) (interface{}, error) {
+	// parse request
+	var req protocol.DescribeKeyRequest
+	if err := readPluginRequest(ctx, &req); err != nil {
+		return nil, err
+	}
+
+	// describe key
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, err := loadSigningKey(cfg, req.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := keySpecFromKey(key.publicKey())
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.DescribeKeyResponse{
+		KeyID:   req.KeyID,
+		KeySpec: spec,
+	}, nil
+}
+
+// keySpecFromKey returns the key spec of the public key.
+func keySpecFromKey(key crypto.PublicKey) (protocol.KeySpec, error) {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		switch key.N.BitLen() {
+		case 2048:
+			return protocol.KeySpecRSA2048, nil
+		case 3072:
+			return protocol.KeySpecRSA3072, nil
+		case 4096:
+			return protocol.KeySpecRSA4096, nil
+		}
+		return "", fmt.Errorf("RSA key of %d bits is not supported", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		switch key.Curve.Params().BitSize {
+		case 256:
+			return protocol.KeySpecEC256, nil
+		case 384:
+			return protocol.KeySpecEC384, nil
+		case 521:
+			return protocol.KeySpecEC521, nil
+		}
+		return "", fmt.Errorf("ECDSA key on curve %s is not supported", key.Curve.Params().Name)
+	}
+	return "", fmt.Errorf("key type %T is not supported", key)
+}
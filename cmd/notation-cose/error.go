@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/microsoft/notation-cose/pkg/cose"
+	"github.com/microsoft/notation-cose/pkg/protocol"
+)
+
+// Exit codes of the plugin.
+const (
+	exitCodeError            = 1
+	exitCodeInvalidRequest   = 2
+	exitCodeAccessDenied     = 3
+	exitCodeInvalidSignature = 10
+	exitCodeUntrustedSigner  = 11
+	exitCodeExpired          = 12
+	exitCodeTimestamp        = 13
+	exitCodeAlgorithm        = 14
+	exitCodeRevoked          = 15
+	exitCodeRevocation       = 16
+)
+
+// verificationErrors maps the verification errors to the exit codes in the
+// order of precedence, since a revocation failure is also an untrusted signer.
+var verificationErrors = []struct {
+	err      error
+	exitCode int
+}{
+	{cose.ErrCertificateRevoked, exitCodeRevoked},
+	{cose.ErrRevocationUnknown, exitCodeRevocation},
+	{cose.ErrTimestampInvalid, exitCodeTimestamp},
+	{cose.ErrSignatureExpired, exitCodeExpired},
+	{cose.ErrSignatureNotYetValid, exitCodeExpired},
+	{cose.ErrAlgorithmNotAllowed, exitCodeAlgorithm},
+	{cose.ErrAlgorithmMismatch, exitCodeAlgorithm},
+	{cose.ErrInvalidSignature, exitCodeInvalidSignature},
+	{cose.ErrInvalidEnvelope, exitCodeInvalidSignature},
+	{cose.ErrUntrustedSigner, exitCodeUntrustedSigner},
+	{cose.ErrMissingCountersignature, exitCodeUntrustedSigner},
+}
+
+// writeError writes the error response in JSON to the standard error, and
+// returns the exit code.
+func writeError(err error) int {
+	resp, exitCode := errorResponse(err)
+	if err := json.NewEncoder(os.Stderr).Encode(resp); err != nil {
+		os.Stderr.WriteString(resp.Message)
+	}
+	return exitCode
+}
+
+// errorResponse converts the error to the error response and the exit code.
+// The verification errors are reported with the error code `ERROR`, and the
+// reason in the error metadata.
+func errorResponse(err error) (*protocol.Error, int) {
+	var resp *protocol.Error
+	if errors.As(err, &resp) {
+		switch resp.Code {
+		case protocol.ErrorCodeValidation, protocol.ErrorCodeUnsupportedContractVersion:
+			return resp, exitCodeInvalidRequest
+		case protocol.ErrorCodeAccessDenied:
+			return resp, exitCodeAccessDenied
+		}
+		return resp, exitCodeError
+	}
+
+	resp = &protocol.Error{
+		Code:    protocol.ErrorCodeGeneric,
+		Message: err.Error(),
+	}
+	if errors.Is(err, os.ErrPermission) {
+		resp.Code = protocol.ErrorCodeAccessDenied
+		return resp, exitCodeAccessDenied
+	}
+	for _, verificationErr := range verificationErrors {
+		if errors.Is(err, verificationErr.err) {
+			resp.Metadata = map[string]string{
+				"reason": verificationErr.err.Error(),
+			}
+			return resp, verificationErr.exitCode
+		}
+	}
+	return resp, exitCodeError
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/microsoft/notation-cose/pkg/cose"
+	"github.com/microsoft/notation-cose/pkg/protocol"
+)
+
+func TestErrorResponse(t *testing.T) {
+	cert := &x509.Certificate{}
+	tests := []struct {
+		name     string
+		err      error
+		code     protocol.ErrorCode
+		reason   error
+		exitCode int
+	}{
+		{
+			name:     "validation error",
+			err:      &protocol.Error{Code: protocol.ErrorCodeValidation},
+			code:     protocol.ErrorCodeValidation,
+			exitCode: exitCodeInvalidRequest,
+		},
+		{
+			name:     "unsupported contract version",
+			err:      &protocol.Error{Code: protocol.ErrorCodeUnsupportedContractVersion},
+			code:     protocol.ErrorCodeUnsupportedContractVersion,
+			exitCode: exitCodeInvalidRequest,
+		},
+		{
+			name:     "access denied",
+			err:      &protocol.Error{Code: protocol.ErrorCodeAccessDenied},
+			code:     protocol.ErrorCodeAccessDenied,
+			exitCode: exitCodeAccessDenied,
+		},
+		{
+			name:     "generic protocol error",
+			err:      &protocol.Error{Code: protocol.ErrorCodeGeneric},
+			code:     protocol.ErrorCodeGeneric,
+			exitCode: exitCodeError,
+		},
+		{
+			name:     "wrapped protocol error",
+			err:      fmt.Errorf("sign: %w", &protocol.Error{Code: protocol.ErrorCodeValidation}),
+			code:     protocol.ErrorCodeValidation,
+			exitCode: exitCodeInvalidRequest,
+		},
+		{
+			name:     "permission denied",
+			err:      &fs.PathError{Op: "open", Path: "key.pem", Err: fs.ErrPermission},
+			code:     protocol.ErrorCodeAccessDenied,
+			exitCode: exitCodeAccessDenied,
+		},
+		{
+			name:     "invalid signature",
+			err:      fmt.Errorf("verify: %w", cose.ErrInvalidSignature),
+			code:     protocol.ErrorCodeGeneric,
+			reason:   cose.ErrInvalidSignature,
+			exitCode: exitCodeInvalidSignature,
+		},
+		{
+			name:     "invalid envelope",
+			err:      fmt.Errorf("verify: %w", cose.ErrInvalidEnvelope),
+			code:     protocol.ErrorCodeGeneric,
+			reason:   cose.ErrInvalidEnvelope,
+			exitCode: exitCodeInvalidSignature,
+		},
+		{
+			name:     "untrusted signer",
+			err:      &cose.CertificateError{Certificate: cert, Err: x509.UnknownAuthorityError{}},
+			code:     protocol.ErrorCodeGeneric,
+			reason:   cose.ErrUntrustedSigner,
+			exitCode: exitCodeUntrustedSigner,
+		},
+		{
+			name:     "threshold not satisfied",
+			err:      &cose.ThresholdError{Threshold: 2, Satisfied: 1},
+			code:     protocol.ErrorCodeGeneric,
+			reason:   cose.ErrUntrustedSigner,
+			exitCode: exitCodeUntrustedSigner,
+		},
+		{
+			name:     "missing countersignature",
+			err:      fmt.Errorf("verify: %w", cose.ErrMissingCountersignature),
+			code:     protocol.ErrorCodeGeneric,
+			reason:   cose.ErrMissingCountersignature,
+			exitCode: exitCodeUntrustedSigner,
+		},
+		{
+			name:     "signature expired",
+			err:      &cose.SignatureExpiredError{},
+			code:     protocol.ErrorCodeGeneric,
+			reason:   cose.ErrSignatureExpired,
+			exitCode: exitCodeExpired,
+		},
+		{
+			name:     "signature not yet valid",
+			err:      &cose.SigningTimeError{},
+			code:     protocol.ErrorCodeGeneric,
+			reason:   cose.ErrSignatureNotYetValid,
+			exitCode: exitCodeExpired,
+		},
+		{
+			name:     "invalid timestamp",
+			err:      &cose.TimestampError{Err: errors.New("missing timestamp")},
+			code:     protocol.ErrorCodeGeneric,
+			reason:   cose.ErrTimestampInvalid,
+			exitCode: exitCodeTimestamp,
+		},
+		{
+			name:     "algorithm not allowed",
+			err:      fmt.Errorf("verify: %w", cose.ErrAlgorithmNotAllowed),
+			code:     protocol.ErrorCodeGeneric,
+			reason:   cose.ErrAlgorithmNotAllowed,
+			exitCode: exitCodeAlgorithm,
+		},
+		{
+			name:     "algorithm mismatch",
+			err:      fmt.Errorf("verify: %w", cose.ErrAlgorithmMismatch),
+			code:     protocol.ErrorCodeGeneric,
+			reason:   cose.ErrAlgorithmMismatch,
+			exitCode: exitCodeAlgorithm,
+		},
+		{
+			name:     "certificate revoked",
+			err:      &cose.CertificateError{Certificate: cert, Err: &cose.RevokedError{Certificate: cert, RevocationTime: time.Now()}},
+			code:     protocol.ErrorCodeGeneric,
+			reason:   cose.ErrCertificateRevoked,
+			exitCode: exitCodeRevoked,
+		},
+		{
+			name:     "revocation unknown",
+			err:      &cose.CertificateError{Certificate: cert, Err: &cose.RevocationCheckError{Certificate: cert}},
+			code:     protocol.ErrorCodeGeneric,
+			reason:   cose.ErrRevocationUnknown,
+			exitCode: exitCodeRevocation,
+		},
+		{
+			name:     "countersignature revoked",
+			err:      &cose.CountersignatureError{Err: &cose.CertificateError{Certificate: cert, Err: &cose.RevokedError{Certificate: cert}}},
+			code:     protocol.ErrorCodeGeneric,
+			reason:   cose.ErrCertificateRevoked,
+			exitCode: exitCodeRevoked,
+		},
+		{
+			name:     "generic error",
+			err:      errors.New("failed"),
+			code:     protocol.ErrorCodeGeneric,
+			exitCode: exitCodeError,
+		},
+		{
+			name:     "file not found",
+			err:      &fs.PathError{Op: "open", Path: "key.pem", Err: os.ErrNotExist},
+			code:     protocol.ErrorCodeGeneric,
+			exitCode: exitCodeError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, exitCode := errorResponse(tt.err)
+			if resp.Code != tt.code {
+				t.Errorf("errorResponse() code = %q, want %q", resp.Code, tt.code)
+			}
+			if exitCode != tt.exitCode {
+				t.Errorf("errorResponse() exit code = %d, want %d", exitCode, tt.exitCode)
+			}
+			var reason string
+			if tt.reason != nil {
+				reason = tt.reason.Error()
+			}
+			if got := resp.Metadata["reason"]; got != reason {
+				t.Errorf("errorResponse() reason = %q, want %q", got, reason)
+			}
+		})
+	}
+}
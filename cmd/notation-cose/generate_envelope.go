@@ -0,0 +1,45 @@
+package main
+
+import (
+	"time"
+
+	"github.com/microsoft/notation-cose/pkg/protocol"
+	"github.com/notaryproject/notation-go"
+	"github.com/urfave/cli/v2"
+)
+
+var generateEnvelopeCommand = pluginCommand(
+	protocol.CommandGenerateEnvelope,
+	"Generate a COSE signature envelope over the payload",
+	runGenerateEnvelope,
+)
+
+func runGenerateEnvelope(ctx *cli.Context) (interface{}, error) {
+	// parse request
+	var req protocol.GenerateEnvelopeRequest
+	if err := readPluginRequest(ctx, &req); err != nil {
+		return nil, err
+	}
+
+	// sign payload
+	var opts notation.SignOptions
+	if req.ExpiryDurationInSeconds > 0 {
+		opts.Expiry = time.Now().Add(time.Duration(req.ExpiryDurationInSeconds) * time.Second)
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	signer, opts, err := getSignerWithOptions(cfg, req.KeyID, opts)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signer.SignPayload(ctx.Context, req.Payload, req.PayloadType, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.GenerateEnvelopeResponse{
+		SignatureEnvelope:     sig,
+		SignatureEnvelopeType: protocol.MediaTypeEnvelope,
+	}, nil
+}
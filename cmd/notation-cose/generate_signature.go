@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"fmt"
+
+	"github.com/microsoft/notation-cose/pkg/cose"
+	"github.com/microsoft/notation-cose/pkg/protocol"
+	"github.com/urfave/cli/v2"
+	gocose "github.com/veraison/go-cose"
+)
+
+var generateSignatureCommand = pluginCommand(
+	protocol.CommandGenerateSignature,
+	"Generate a raw signature over the payload",
+	runGenerateSignature,
+)
+
+// signingAlgorithms maps the COSE algorithms to the hash algorithms and the
+// signing algorithms of the Notation plugin contract.
+var signingAlgorithms = map[gocose.Algorithm]struct {
+	hash     protocol.HashAlgorithm
+	hashFunc crypto.Hash
+	alg      protocol.SigningAlgorithm
+}{
+	gocose.AlgorithmPS256: {protocol.HashAlgorithmSHA256, crypto.SHA256, protocol.SigningAlgorithmRSASSAPSSSHA256},
+	gocose.AlgorithmPS384: {protocol.HashAlgorithmSHA384, crypto.SHA384, protocol.SigningAlgorithmRSASSAPSSSHA384},
+	gocose.AlgorithmPS512: {protocol.HashAlgorithmSHA512, crypto.SHA512, protocol.SigningAlgorithmRSASSAPSSSHA512},
+	gocose.AlgorithmES256: {protocol.HashAlgorithmSHA256, crypto.SHA256, protocol.SigningAlgorithmECDSASHA256},
+	gocose.AlgorithmES384: {protocol.HashAlgorithmSHA384, crypto.SHA384, protocol.SigningAlgorithmECDSASHA384},
+	gocose.AlgorithmES512: {protocol.HashAlgorithmSHA512, crypto.SHA512, protocol.SigningAlgorithmECDSASHA512},
+}
+
+func runGenerateSignature(ctx *cli.Context) (interface{}, error) {
+	// parse request
+	var req protocol.GenerateSignatureRequest
+	if err := readPluginRequest(ctx, &req); err != nil {
+		return nil, err
+	}
+
+	// resolve key and algorithm
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, err := loadSigningKey(cfg, req.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := keySpecFromKey(key.publicKey())
+	if err != nil {
+		return nil, err
+	}
+	if req.KeySpec != spec {
+		return nil, validationError("key spec %q does not match the key %q of spec %q", req.KeySpec, req.KeyID, spec)
+	}
+	alg, err := key.algorithm()
+	if err != nil {
+		return nil, err
+	}
+	algs, ok := signingAlgorithms[alg]
+	if !ok {
+		return nil, validationError("algorithm %v is not supported", alg)
+	}
+	if req.Hash != algs.hash {
+		return nil, validationError("hash algorithm %q does not match the key spec %q", req.Hash, spec)
+	}
+
+	// sign payload
+	h := algs.hashFunc.New()
+	h.Write(req.Payload)
+	digest := h.Sum(nil)
+	var sig []byte
+	if key.external != nil {
+		sig, err = signExternal(ctx, key, alg, digest)
+	} else {
+		var signer gocose.Signer
+		if signer, err = gocose.NewSigner(alg, key.key); err == nil {
+			sig, err = signer.Sign(rand.Reader, digest)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	certChain := make([][]byte, 0, len(key.certs))
+	for _, cert := range key.certs {
+		certChain = append(certChain, cert.Raw)
+	}
+	return &protocol.GenerateSignatureResponse{
+		KeyID:            req.KeyID,
+		Signature:        sig,
+		SigningAlgorithm: algs.alg,
+		CertificateChain: certChain,
+	}, nil
+}
+
+// signExternal signs the digest by the external signer of the key, and
+// verifies the returned signature against the signing certificate.
+func signExternal(ctx *cli.Context, key *signingKey, alg gocose.Algorithm, digest []byte) ([]byte, error) {
+	sig, err := key.external.Sign(ctx.Context, alg, digest)
+	if err != nil {
+		return nil, fmt.Errorf("external signer failed: %w", err)
+	}
+	verifier, err := gocose.NewVerifier(alg, key.publicKey())
+	if err != nil {
+		return nil, err
+	}
+	if err := verifier.Verify(digest, sig); err != nil {
+		return nil, fmt.Errorf("external signer returned a signature not matching the signing certificate: %w", cose.ErrInvalidSignature)
+	}
+	return sig, nil
+}
@@ -8,16 +8,34 @@ import (
 )
 
 func main() {
-	app := &cli.App{
+	if err := newApp().Run(os.Args); err != nil {
+		os.Exit(writeError(err))
+	}
+}
+
+// newApp creates the command line app of the plugin.
+func newApp() *cli.App {
+	return &cli.App{
 		Name:    "notation-cose",
 		Usage:   "COSE Plugin for Notation",
 		Version: version.GetVersion(),
+		Flags: []cli.Flag{
+			configFlag,
+		},
 		Commands: []*cli.Command{
 			signCommand,
 			verifyCommand,
+			signBlobCommand,
+			verifyBlobCommand,
+			getMetadataCommand,
+			describeKeyCommand,
+			generateSignatureCommand,
+			generateEnvelopeCommand,
+			verifySignatureCommand,
+			prepareSignatureCommand,
+			attachSignatureCommand,
+			countersignCommand,
+			configCommand,
 		},
 	}
-	if err := app.Run(os.Args); err != nil {
-		os.Stderr.WriteString(err.Error())
-	}
 }
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/microsoft/notation-cose/internal/config"
+	"github.com/microsoft/notation-cose/pkg/cose"
+	"github.com/microsoft/notation-cose/pkg/protocol"
+	"github.com/notaryproject/notation-go"
+	"github.com/notaryproject/notation-go/crypto/cryptoutil"
+	"github.com/urfave/cli/v2"
+	gocose "github.com/veraison/go-cose"
+)
+
+// offlineSignerFlags specify the signing certificate chain and the algorithm
+// of the two-phase signing.
+var offlineSignerFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:     "cert",
+		Aliases:  []string{"c"},
+		Usage:    "path to the signing certificate chain",
+		Required: true,
+	},
+	&cli.StringFlag{
+		Name:  "algorithm",
+		Usage: "signing algorithm, such as PS384, which is picked up by the signing certificate if not present",
+	},
+}
+
+// outputFlag writes the output to the file instead of stdout.
+var outputFlag = &cli.StringFlag{
+	Name:    "output",
+	Aliases: []string{"o"},
+	Usage:   "write output to the file instead of stdout",
+}
+
+var prepareSignatureCommand = &cli.Command{
+	Name:      "prepare-signature",
+	Usage:     "Prepare the bytes to be signed offline for an artifact or a blob",
+	ArgsUsage: "[<request>]",
+	Flags: append([]cli.Flag{
+		requestFileFlag,
+		&cli.StringFlag{
+			Name:  "blob",
+			Usage: "path to the blob to be signed with detached payload instead of the artifact in the request",
+		},
+		&cli.DurationFlag{
+			Name:    "expiry",
+			Aliases: []string{"e"},
+			Usage:   "expire duration of the signature of the blob",
+		},
+		outputFlag,
+	}, offlineSignerFlags...),
+	Action: runPrepareSignature,
+}
+
+var attachSignatureCommand = &cli.Command{
+	Name:  "attach-signature",
+	Usage: "Attach a signature produced offline to the prepared signing request",
+	Flags: append([]cli.Flag{
+		&cli.StringFlag{
+			Name:     "signing-request",
+			Usage:    "path to the signing request generated by prepare-signature",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "signature",
+			Aliases:  []string{"s"},
+			Usage:    "path to the raw signature over the digest of the signing request",
+			Required: true,
+		},
+		&cli.StringSliceFlag{
+			Name:  "tsa",
+			Usage: "URL of the timestamping authority, which are tried in order",
+		},
+		stapleRevocationFlag,
+		outputFlag,
+	}, offlineSignerFlags...),
+	Action: runAttachSignature,
+}
+
+func runPrepareSignature(ctx *cli.Context) error {
+	signer, err := getOfflineSigner(ctx)
+	if err != nil {
+		return err
+	}
+
+	// prepare signing request
+	var req *cose.SigningRequest
+	if path := ctx.String("blob"); path != "" {
+		if ctx.Args().Present() || ctx.IsSet(requestFileFlag.Name) {
+			return errors.New("blob and request are mutually exclusive")
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var opts notation.SignOptions
+		if expiry := ctx.Duration("expiry"); expiry != 0 {
+			opts.Expiry = time.Now().Add(expiry)
+		}
+		if req, err = signer.PrepareBlob(content, opts); err != nil {
+			return err
+		}
+	} else {
+		r, err := openRequest(ctx)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		signReq, err := protocol.DecodeSignRequest(r)
+		if err != nil {
+			return err
+		}
+		if err := signReq.Validate(); err != nil {
+			return err
+		}
+		req, err = signer.Prepare(signReq.Descriptor, notation.SignOptions{
+			Expiry: signReq.SignOptions.Expiry,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// write response
+	out, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return writeOutput(ctx, out)
+}
+
+func runAttachSignature(ctx *cli.Context) error {
+	signer, err := getOfflineSigner(ctx)
+	if err != nil {
+		return err
+	}
+	if ctx.Bool(stapleRevocationFlag.Name) {
+		signer.RevocationFetcher = &cose.HTTPFetcher{}
+	}
+
+	// read signing request and signature
+	data, err := os.ReadFile(ctx.String("signing-request"))
+	if err != nil {
+		return err
+	}
+	var req cose.SigningRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return err
+	}
+	sig, err := os.ReadFile(ctx.String("signature"))
+	if err != nil {
+		return err
+	}
+	var opts notation.SignOptions
+	if endpoints := ctx.StringSlice("tsa"); len(endpoints) > 0 {
+		if signer.Timestamping, err = newTimestampPolicy(endpoints, config.SignOptions{}); err != nil {
+			return err
+		}
+	}
+
+	// attach signature
+	envelope, err := signer.Attach(ctx.Context, &req, sig, opts)
+	if err != nil {
+		return err
+	}
+	return writeOutput(ctx, envelope)
+}
+
+// getOfflineSigner creates the signer of the two-phase signing.
+func getOfflineSigner(ctx *cli.Context) (*cose.Signer, error) {
+	certs, err := cryptoutil.ReadCertificateFile(ctx.String("cert"))
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("missing signer certificate chain")
+	}
+	var alg gocose.Algorithm
+	if name := ctx.String("algorithm"); name != "" {
+		alg, err = cose.ParseAlgorithm(name)
+	} else {
+		alg, err = cose.AlgorithmFromKey(certs[0].PublicKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cose.NewOfflineSigner(alg, certs)
+}
+
+// writeOutput writes the output to the file specified by the output flag, or
+// to the standard output.
+func writeOutput(ctx *cli.Context, out []byte) error {
+	if path := ctx.String(outputFlag.Name); path != "" {
+		return os.WriteFile(path, out, 0644)
+	}
+	_, err := os.Stdout.Write(out)
+	return err
+}
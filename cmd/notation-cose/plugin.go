@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/microsoft/notation-cose/internal/version"
+	"github.com/microsoft/notation-cose/pkg/protocol"
+	"github.com/urfave/cli/v2"
+)
+
+var getMetadataCommand = pluginCommand(
+	protocol.CommandGetMetadata,
+	"Get the metadata of the plugin",
+	runGetMetadata,
+)
+
+// pluginCommand creates a command of the Notation plugin contract, which reads
+// the request from the standard input, and writes the response to the
+// standard output of the app.
+func pluginCommand(name, usage string, run func(ctx *cli.Context) (interface{}, error)) *cli.Command {
+	return &cli.Command{
+		Name:  name,
+		Usage: usage,
+		Flags: []cli.Flag{
+			requestFileFlag,
+		},
+		Action: func(ctx *cli.Context) error {
+			resp, err := run(ctx)
+			if err != nil {
+				return err
+			}
+			return json.NewEncoder(ctx.App.Writer).Encode(resp)
+		},
+	}
+}
+
+func runGetMetadata(ctx *cli.Context) (interface{}, error) {
+	return &protocol.GetMetadataResponse{
+		Name:                      ctx.App.Name,
+		Description:               ctx.App.Usage,
+		Version:                   version.GetVersion(),
+		URL:                       "https://github.com/microsoft/notation-cose",
+		SupportedContractVersions: []string{protocol.ContractVersion},
+		Capabilities: []protocol.Capability{
+			protocol.CapabilitySignatureGenerator,
+			protocol.CapabilityEnvelopeGenerator,
+			protocol.CapabilityTrustedIdentityVerifier,
+			protocol.CapabilityRevocationCheckVerifier,
+		},
+	}, nil
+}
+
+// readPluginRequest decodes the request from the standard input or the
+// request file, and validates the request including its contract version.
+func readPluginRequest(ctx *cli.Context, req interface{ Validate() error }) error {
+	if err := readRequest(ctx, req); err != nil {
+		return &protocol.Error{
+			Code:    protocol.ErrorCodeValidation,
+			Message: fmt.Sprintf("invalid request: %v", err),
+		}
+	}
+	return req.Validate()
+}
+
+// validationError creates an error response for invalid requests.
+func validationError(format string, a ...interface{}) error {
+	return &protocol.Error{
+		Code:    protocol.ErrorCodeValidation,
+		Message: fmt.Sprintf(format, a...),
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/microsoft/notation-cose/pkg/protocol"
+	gocose "github.com/veraison/go-cose"
+)
+
+func TestGetMetadata(t *testing.T) {
+	var resp protocol.GetMetadataResponse
+	runPluginCommand(t, "", protocol.CommandGetMetadata, nil, &resp)
+	if resp.Name != "notation-cose" {
+		t.Errorf("GetMetadataResponse.Name = %q, want %q", resp.Name, "notation-cose")
+	}
+	if len(resp.SupportedContractVersions) != 1 || resp.SupportedContractVersions[0] != protocol.ContractVersion {
+		t.Errorf("GetMetadataResponse.SupportedContractVersions = %q, want %q", resp.SupportedContractVersions, []string{protocol.ContractVersion})
+	}
+	if len(resp.Capabilities) != 4 {
+		t.Errorf("GetMetadataResponse.Capabilities = %q, want 4 capabilities", resp.Capabilities)
+	}
+}
+
+func TestDescribeKey(t *testing.T) {
+	configPath, _ := newTestKeyConfig(t)
+	var resp protocol.DescribeKeyResponse
+	runPluginCommand(t, configPath, protocol.CommandDescribeKey, &protocol.DescribeKeyRequest{
+		ContractVersion: protocol.ContractVersion,
+		KeyID:           "test",
+	}, &resp)
+	if resp.KeyID != "test" {
+		t.Errorf("DescribeKeyResponse.KeyID = %q, want %q", resp.KeyID, "test")
+	}
+	if resp.KeySpec != protocol.KeySpecEC256 {
+		t.Errorf("DescribeKeyResponse.KeySpec = %q, want %q", resp.KeySpec, protocol.KeySpecEC256)
+	}
+}
+
+func TestGenerateSignature(t *testing.T) {
+	configPath, cert := newTestKeyConfig(t)
+	payload := []byte("payload")
+	var resp protocol.GenerateSignatureResponse
+	runPluginCommand(t, configPath, protocol.CommandGenerateSignature, &protocol.GenerateSignatureRequest{
+		ContractVersion: protocol.ContractVersion,
+		KeyID:           "test",
+		KeySpec:         protocol.KeySpecEC256,
+		Hash:            protocol.HashAlgorithmSHA256,
+		Payload:         payload,
+	}, &resp)
+	if resp.KeyID != "test" {
+		t.Errorf("GenerateSignatureResponse.KeyID = %q, want %q", resp.KeyID, "test")
+	}
+	if resp.SigningAlgorithm != protocol.SigningAlgorithmECDSASHA256 {
+		t.Errorf("GenerateSignatureResponse.SigningAlgorithm = %q, want %q", resp.SigningAlgorithm, protocol.SigningAlgorithmECDSASHA256)
+	}
+	if len(resp.CertificateChain) != 1 || !bytes.Equal(resp.CertificateChain[0], cert.Raw) {
+		t.Error("GenerateSignatureResponse.CertificateChain does not contain the signing certificate")
+	}
+	verifier, err := gocose.NewVerifier(gocose.AlgorithmES256, cert.PublicKey)
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+	h := crypto.SHA256.New()
+	h.Write(payload)
+	if err := verifier.Verify(h.Sum(nil), resp.Signature); err != nil {
+		t.Errorf("GenerateSignatureResponse.Signature is invalid: %v", err)
+	}
+}
+
+func TestGenerateEnvelope(t *testing.T) {
+	configPath, _ := newTestKeyConfig(t)
+	var resp protocol.GenerateEnvelopeResponse
+	runPluginCommand(t, configPath, protocol.CommandGenerateEnvelope, &protocol.GenerateEnvelopeRequest{
+		ContractVersion:       protocol.ContractVersion,
+		KeyID:                 "test",
+		Payload:               []byte(`{"targetArtifact":{}}`),
+		PayloadType:           protocol.MediaTypePayload,
+		SignatureEnvelopeType: protocol.MediaTypeEnvelope,
+	}, &resp)
+	if resp.SignatureEnvelopeType != protocol.MediaTypeEnvelope {
+		t.Errorf("GenerateEnvelopeResponse.SignatureEnvelopeType = %q, want %q", resp.SignatureEnvelopeType, protocol.MediaTypeEnvelope)
+	}
+	var msg gocose.Sign1Message
+	if err := msg.UnmarshalCBOR(resp.SignatureEnvelope); err != nil {
+		t.Errorf("GenerateEnvelopeResponse.SignatureEnvelope is not a COSE_Sign1 message: %v", err)
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	_, cert := newTestKeyConfig(t)
+	tests := []struct {
+		name     string
+		identity string
+		want     bool
+	}{
+		{"trusted identity", "x509.subject: CN=test", true},
+		{"untrusted identity", "x509.subject: CN=other", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp protocol.VerifySignatureResponse
+			runPluginCommand(t, "", protocol.CommandVerifySignature, &protocol.VerifySignatureRequest{
+				ContractVersion: protocol.ContractVersion,
+				Signature: protocol.Signature{
+					CertificateChain: [][]byte{cert.Raw},
+				},
+				TrustPolicy: protocol.TrustPolicy{
+					TrustedIdentities:     []string{tt.identity},
+					SignatureVerification: []protocol.Capability{protocol.CapabilityTrustedIdentityVerifier},
+				},
+			}, &resp)
+			if len(resp.VerificationResults) != 1 {
+				t.Fatalf("VerifySignatureResponse.VerificationResults = %v, want 1 result", resp.VerificationResults)
+			}
+			result := resp.VerificationResults[protocol.CapabilityTrustedIdentityVerifier]
+			if result == nil {
+				t.Fatalf("VerifySignatureResponse.VerificationResults misses %q", protocol.CapabilityTrustedIdentityVerifier)
+			}
+			if result.Success != tt.want {
+				t.Errorf("VerificationResult.Success = %v, want %v: %s", result.Success, tt.want, result.Reason)
+			}
+			if !tt.want && result.Reason == "" {
+				t.Error("VerificationResult.Reason is empty")
+			}
+		})
+	}
+}
+
+func TestPluginCommandsUnsupportedContractVersion(t *testing.T) {
+	configPath, cert := newTestKeyConfig(t)
+	tests := []struct {
+		command string
+		req     interface{}
+	}{
+		{protocol.CommandDescribeKey, &protocol.DescribeKeyRequest{
+			ContractVersion: "0.1",
+			KeyID:           "test",
+		}},
+		{protocol.CommandGenerateSignature, &protocol.GenerateSignatureRequest{
+			ContractVersion: "0.1",
+			KeyID:           "test",
+			KeySpec:         protocol.KeySpecEC256,
+			Hash:            protocol.HashAlgorithmSHA256,
+			Payload:         []byte("payload"),
+		}},
+		{protocol.CommandGenerateEnvelope, &protocol.GenerateEnvelopeRequest{
+			ContractVersion:       "0.1",
+			KeyID:                 "test",
+			Payload:               []byte(`{"targetArtifact":{}}`),
+			PayloadType:           protocol.MediaTypePayload,
+			SignatureEnvelopeType: protocol.MediaTypeEnvelope,
+		}},
+		{protocol.CommandVerifySignature, &protocol.VerifySignatureRequest{
+			ContractVersion: "0.1",
+			Signature: protocol.Signature{
+				CertificateChain: [][]byte{cert.Raw},
+			},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			stdout, err := runPlugin(t, configPath, tt.command, tt.req)
+			if err == nil {
+				t.Fatalf("%s succeeded, want error", tt.command)
+			}
+			if len(stdout) != 0 {
+				t.Errorf("%s wrote response %s on error", tt.command, stdout)
+			}
+			resp, exitCode := errorResponse(err)
+			if resp.Code != protocol.ErrorCodeUnsupportedContractVersion {
+				t.Errorf("%s error code = %q, want %q", tt.command, resp.Code, protocol.ErrorCodeUnsupportedContractVersion)
+			}
+			if exitCode != exitCodeInvalidRequest {
+				t.Errorf("%s exit code = %d, want %d", tt.command, exitCode, exitCodeInvalidRequest)
+			}
+		})
+	}
+}
+
+func TestPluginCommandsInvalidRequest(t *testing.T) {
+	for _, command := range []string{
+		protocol.CommandDescribeKey,
+		protocol.CommandGenerateSignature,
+		protocol.CommandGenerateEnvelope,
+		protocol.CommandVerifySignature,
+	} {
+		t.Run(command, func(t *testing.T) {
+			_, err := runPlugin(t, "", command, []byte(`{"contractVersion":`))
+			if err == nil {
+				t.Fatalf("%s succeeded, want error", command)
+			}
+			resp, exitCode := errorResponse(err)
+			if resp.Code != protocol.ErrorCodeValidation {
+				t.Errorf("%s error code = %q, want %q", command, resp.Code, protocol.ErrorCodeValidation)
+			}
+			if exitCode != exitCodeInvalidRequest {
+				t.Errorf("%s exit code = %d, want %d", command, exitCode, exitCodeInvalidRequest)
+			}
+		})
+	}
+}
+
+// runPluginCommand runs the plugin command with the request on the standard
+// input, and decodes the response from the standard output.
+func runPluginCommand(t *testing.T, configPath, command string, req, resp interface{}) {
+	t.Helper()
+	stdout, err := runPlugin(t, configPath, command, req)
+	if err != nil {
+		t.Fatalf("%s error = %v", command, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(stdout))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(resp); err != nil {
+		t.Fatalf("%s response %s does not match the schema: %v", command, stdout, err)
+	}
+}
+
+// runPlugin runs the plugin command with the request on the standard input,
+// and returns the standard output. Requests of []byte are written verbatim.
+func runPlugin(t *testing.T, configPath, command string, req interface{}) ([]byte, error) {
+	t.Helper()
+	var stdin []byte
+	switch req := req.(type) {
+	case nil:
+	case []byte:
+		stdin = req
+	default:
+		var err error
+		if stdin, err = json.Marshal(req); err != nil {
+			t.Fatalf("json.Marshal() error = %v", err)
+		}
+	}
+	if configPath == "" {
+		configPath = filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(configPath, []byte("{}"), 0600); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+	}
+	var stdout bytes.Buffer
+	app := newApp()
+	app.Reader = bytes.NewReader(stdin)
+	app.Writer = &stdout
+	app.ErrWriter = io.Discard
+	err := app.Run([]string{"notation-cose", "--config", configPath, command})
+	return stdout.Bytes(), err
+}
+
+// newTestKeyConfig creates a config file with the key profile `test` of a
+// self-signed ECDSA P-256 certificate.
+func newTestKeyConfig(t *testing.T) (string, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey() error = %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		BasicConstraintsValid: true,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate() error = %v", err)
+	}
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate() error = %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("x509.MarshalPKCS8PrivateKey() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"key.pem":  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+		"cert.pem": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		"config.json": []byte(fmt.Sprintf(`{"keys":{"test":{"keyFile":%q,"certificateChainFile":%q}}}`,
+			"key.pem", "cert.pem")),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+	}
+	return filepath.Join(dir, "config.json"), cert
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// requestFileFlag specifies the file containing the JSON request.
+var requestFileFlag = &cli.StringFlag{
+	Name:  "request-file",
+	Usage: "path to the JSON request, which is read from the standard input if neither the file nor the argument is present",
+}
+
+// readRequest decodes the JSON request from the request file, the command line
+// argument, or the standard input.
+func readRequest(ctx *cli.Context, req interface{}) error {
+	r, err := openRequest(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return json.NewDecoder(r).Decode(req)
+}
+
+// openRequest opens the JSON request from the request file, the command line
+// argument, or the standard input in order. The request is decoded as a
+// stream so that large signatures and certificate chains are not limited by
+// the size of the command line.
+func openRequest(ctx *cli.Context) (io.ReadCloser, error) {
+	args := ctx.Args()
+	if path := ctx.String(requestFileFlag.Name); path != "" {
+		if args.Present() {
+			return nil, errors.New("request file and request argument are mutually exclusive")
+		}
+		return os.Open(path)
+	}
+	if args.Present() {
+		if args.Len() != 1 {
+			return nil, errors.New("too many arguments")
+		}
+		return io.NopCloser(strings.NewReader(args.First())), nil
+	}
+	return io.NopCloser(ctx.App.Reader), nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+func TestReadRequest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "request.json")
+	if err := os.WriteFile(path, []byte(`{"source":"file"}`), 0600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	const stdin = `{"source":"stdin"}`
+	const arg = `{"source":"argument"}`
+	tests := []struct {
+		name  string
+		stdin string
+		args  []string
+		want  string
+	}{
+		{"stdin", stdin, nil, "stdin"},
+		{"request file", "", []string{"--request-file", path}, "file"},
+		{"argument", "", []string{arg}, "argument"},
+		{"request file over stdin", stdin, []string{"--request-file", path}, "file"},
+		{"argument over stdin", stdin, []string{arg}, "argument"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := runReadRequest(tt.stdin, tt.args...)
+			if err != nil {
+				t.Fatalf("readRequest() error = %v", err)
+			}
+			if got := req["source"]; got != tt.want {
+				t.Errorf("readRequest() source = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadRequestErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "request.json")
+	if err := os.WriteFile(path, []byte(`{"source":"file"}`), 0600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"request file and argument", []string{"--request-file", path, `{"source":"argument"}`}},
+		{"too many arguments", []string{`{"source":"argument"}`, `{}`}},
+		{"missing request file", []string{"--request-file", filepath.Join(t.TempDir(), "missing.json")}},
+		{"malformed argument", []string{`{"source":`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := runReadRequest(`{"source":"stdin"}`, tt.args...); err == nil {
+				t.Error("readRequest() error = nil, want error")
+			}
+		})
+	}
+}
+
+// runReadRequest runs a command reading the request with the standard input
+// and the command line arguments.
+func runReadRequest(stdin string, args ...string) (map[string]string, error) {
+	var req map[string]string
+	app := &cli.App{
+		Reader:    strings.NewReader(stdin),
+		Writer:    io.Discard,
+		ErrWriter: io.Discard,
+		Commands: []*cli.Command{
+			{
+				Name:  "test",
+				Flags: []cli.Flag{requestFileFlag},
+				Action: func(ctx *cli.Context) error {
+					return readRequest(ctx, &req)
+				},
+			},
+		},
+	}
+	err := app.Run(append([]string{"notation-cose", "test"}, args...))
+	return req, err
+}
@@ -2,46 +2,87 @@ package main
 
 import (
 	"crypto"
-	"crypto/tls"
-	"encoding/json"
+	"crypto/x509"
 	"errors"
 	"os"
-	"strings"
+	"time"
 
+	"github.com/microsoft/notation-cose/internal/config"
+	"github.com/microsoft/notation-cose/internal/keyutil"
 	"github.com/microsoft/notation-cose/pkg/cose"
 	"github.com/microsoft/notation-cose/pkg/protocol"
 	"github.com/notaryproject/notation-go"
 	"github.com/notaryproject/notation-go/crypto/cryptoutil"
-	"github.com/notaryproject/notation-go/crypto/timestamp"
 	"github.com/urfave/cli/v2"
+	gocose "github.com/veraison/go-cose"
 )
 
 var signCommand = &cli.Command{
 	Name:      "sign",
 	Usage:     "Sign artifacts in COSE",
-	ArgsUsage: "<reference>",
-	Action:    runSign,
+	ArgsUsage: "[<request>]",
+	Flags: []cli.Flag{
+		requestFileFlag,
+		stapleRevocationFlag,
+		&cli.BoolFlag{
+			Name:  "multi-signer",
+			Usage: "generate a COSE_Sign envelope, to which other signers append their signatures",
+		},
+		&cli.StringFlag{
+			Name:  "append",
+			Usage: "path to the COSE_Sign envelope to which the signature is appended",
+		},
+	},
+	Action: runSign,
 }
 
-func runSign(ctx *cli.Context) error {
-	// initialize
-	args := ctx.Args()
-	if args.Len() != 1 {
-		return errors.New("missing request")
-	}
+// stapleRevocationFlag configures the signer to staple the revocation data of
+// the certificate chain in the signature.
+var stapleRevocationFlag = &cli.BoolFlag{
+	Name:  "staple-revocation",
+	Usage: "staple OCSP responses or CRLs of the certificate chain in the signature",
+}
 
+func runSign(ctx *cli.Context) error {
 	// parse request
-	var req protocol.SignRequest
-	if err := json.Unmarshal([]byte(args.Get(0)), &req); err != nil {
+	r, err := openRequest(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	req, err := protocol.DecodeSignRequest(r)
+	if err != nil {
+		return err
+	}
+	if err := req.Validate(); err != nil {
 		return err
 	}
 
 	// sign artifact
-	signer, opts, err := getSignerWithOptions(req.KMSProfile.ID, req.SignOptions)
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	signer, opts, err := getSignerWithOptions(cfg, req.KMSProfile.ID, notation.SignOptions{
+		Expiry: req.SignOptions.Expiry,
+	})
 	if err != nil {
 		return err
 	}
-	sig, err := signer.Sign(ctx.Context, req.Descriptor, opts)
+	if ctx.Bool(stapleRevocationFlag.Name) {
+		signer.RevocationFetcher = &cose.HTTPFetcher{}
+	}
+	var sig []byte
+	if path := ctx.String("append"); path != "" {
+		var envelope []byte
+		if envelope, err = os.ReadFile(path); err == nil {
+			sig, err = signer.AppendSignature(ctx.Context, envelope, req.Descriptor, opts)
+		}
+	} else if ctx.Bool("multi-signer") {
+		sig, err = signer.SignMulti(ctx.Context, req.Descriptor, opts)
+	} else {
+		sig, err = signer.Sign(ctx.Context, req.Descriptor, opts)
+	}
 	if err != nil {
 		return err
 	}
@@ -51,58 +92,161 @@ func runSign(ctx *cli.Context) error {
 	return err
 }
 
-func getSignerWithOptions(keyInfo string, opts notation.SignOptions) (notation.Signer, notation.SignOptions, error) {
-	// parse options
-	items := strings.SplitN(keyInfo, ":", 3)
-	if len(items) < 2 {
-		return nil, opts, errors.New("missing signing key pair")
+// signingKey is a signing key bundled with its certificate chain.
+type signingKey struct {
+	key      crypto.Signer
+	external cose.ExternalSigner
+	certs    []*x509.Certificate
+	config   *config.Key
+}
+
+// loadSigningKey loads the signing key referenced by the name of a key profile
+// in the configuration, or described by the key descriptor in JSON or in the
+// legacy format of `<key path>:<cert path>[:<timestamp url>]`.
+func loadSigningKey(cfg *config.Config, keyID string) (*signingKey, error) {
+	keyConfig, err := cfg.ResolveKey(keyID)
+	if err != nil {
+		return nil, err
 	}
-	keyPath := items[0]
-	certPath := items[1]
-	var tsEndpoint string
-	if len(items) > 2 {
-		tsEndpoint = items[2]
+
+	var passphrase keyutil.PassphraseFunc
+	if keyConfig.Passphrase != nil {
+		passphrase = keyConfig.Passphrase.Read
 	}
 
 	// read key / cert pair
-	keyPEM, err := os.ReadFile(keyPath)
-	if err != nil {
-		return nil, opts, err
+	key := &signingKey{
+		config: keyConfig,
 	}
-	certPEM, err := os.ReadFile(certPath)
-	if err != nil {
-		return nil, opts, err
+	if keyConfig.PKCS12File != "" {
+		data, err := os.ReadFile(keyConfig.PKCS12File)
+		if err != nil {
+			return nil, err
+		}
+		key.key, key.certs, err = keyutil.ParsePKCS12(data, passphrase)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		certPEM, err := os.ReadFile(keyConfig.CertificateChainFile)
+		if err != nil {
+			return nil, err
+		}
+		key.certs, err = cryptoutil.ParseCertificatePEM(certPEM)
+		if err != nil {
+			return nil, err
+		}
+		if signer := keyConfig.ExternalSigner; signer != nil {
+			key.external = newExternalSigner(signer)
+		} else {
+			keyPEM, err := os.ReadFile(keyConfig.KeyFile)
+			if err != nil {
+				return nil, err
+			}
+			key.key, err = keyutil.ParsePrivateKeyPEM(keyPEM, passphrase)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
-	keyPair, err := tls.X509KeyPair(certPEM, keyPEM)
-	if err != nil {
-		return nil, opts, err
+	if len(key.certs) == 0 {
+		return nil, errors.New("missing signer certificate chain")
+	}
+	if key.key != nil {
+		if err := keyutil.CheckKeyPair(key.key, key.certs[0]); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// newExternalSigner creates the external signer of the backend.
+func newExternalSigner(signer *config.ExternalSigner) cose.ExternalSigner {
+	if signer.URL != "" {
+		return &cose.HTTPSigner{
+			URL:   signer.URL,
+			KeyID: signer.KeyID,
+		}
+	}
+	return &cose.CommandSigner{
+		Command: signer.Command,
+		KeyID:   signer.KeyID,
+	}
+}
+
+// publicKey returns the public key of the signing certificate.
+func (k *signingKey) publicKey() crypto.PublicKey {
+	return k.certs[0].PublicKey
+}
+
+// algorithm returns the signing algorithm of the key.
+func (k *signingKey) algorithm() (gocose.Algorithm, error) {
+	if k.config.Algorithm != "" {
+		return cose.ParseAlgorithm(k.config.Algorithm)
 	}
+	return cose.AlgorithmFromKey(k.publicKey())
+}
 
-	// parse cert
-	certs, err := cryptoutil.ParseCertificatePEM(certPEM)
+// signer creates the signer of the key with the algorithm.
+func (k *signingKey) signer(alg gocose.Algorithm) (*cose.Signer, error) {
+	if k.external != nil {
+		return cose.NewSignerWithExternalSigner(alg, k.external, k.certs)
+	}
+	return cose.NewSignerWithCertificateChain(alg, k.key, k.certs)
+}
+
+// getSignerWithOptions creates the signer of the key, and refines the signing
+// options with the defaults of the configuration.
+func getSignerWithOptions(cfg *config.Config, keyID string, opts notation.SignOptions) (*cose.Signer, notation.SignOptions, error) {
+	key, err := loadSigningKey(cfg, keyID)
 	if err != nil {
 		return nil, opts, err
 	}
 
 	// construct signer
-	privateKey, ok := keyPair.PrivateKey.(crypto.Signer)
-	if !ok {
-		return nil, opts, errors.New("unsupported private key")
+	alg, err := key.algorithm()
+	if err != nil {
+		return nil, opts, err
 	}
-	signer, err := cose.NewSigner(privateKey, certs)
+	signer, err := key.signer(alg)
 	if err != nil {
 		return nil, opts, err
 	}
+	signer.ProtectedHeaders = key.config.Headers.ProtectedHeaders()
+	signer.UnprotectedHeaders = key.config.Headers.UnprotectedHeaders()
+	signer.CriticalHeaders = key.config.Headers.CriticalHeaders()
+	if cfg.SignOptions.StapleRevocation {
+		signer.RevocationFetcher = &cose.HTTPFetcher{}
+	}
+	if opts.Expiry.IsZero() && cfg.SignOptions.Expiry() > 0 {
+		opts.Expiry = time.Now().Add(cfg.SignOptions.Expiry())
+	}
 
-	// hack: refine options
-	// notation#feat-kv-extensibility uses an older version of notation-go-lib,
-	// which does not support TSA in options.
-	if tsEndpoint != "" {
-		tsa, err := timestamp.NewHTTPTimestamper(nil, tsEndpoint)
-		if err != nil {
+	// timestamp by the TSAs in order
+	if len(key.config.TSAEndpoints) > 0 {
+		if signer.Timestamping, err = newTimestampPolicy(key.config.TSAEndpoints, cfg.SignOptions); err != nil {
 			return nil, opts, err
 		}
-		opts.TSA = tsa
 	}
 	return signer, opts, nil
 }
+
+// newTimestampPolicy creates the timestamp policy of the TSA endpoints with the
+// retries and the quorum of the signing options.
+func newTimestampPolicy(endpoints []string, opts config.SignOptions) (*cose.TimestampPolicy, error) {
+	policy := &cose.TimestampPolicy{
+		Retries: opts.TSARetries,
+		Quorum:  opts.TSAQuorum,
+	}
+	for _, endpoint := range endpoints {
+		ts, err := cose.NewHTTPTimestamper(nil, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		policy.TSAs = append(policy.TSAs, ts)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
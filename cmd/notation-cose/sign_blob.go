@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/microsoft/notation-cose/pkg/cose"
+	"github.com/notaryproject/notation-go"
+	"github.com/urfave/cli/v2"
+)
+
+var signBlobCommand = &cli.Command{
+	Name:      "sign-blob",
+	Usage:     "Sign arbitrary blobs in COSE with detached payload",
+	ArgsUsage: "<file>",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "key",
+			Aliases:  []string{"k"},
+			Usage:    "signing key profile, key descriptor in JSON, or key in the format of <key_path>:<cert_path>[:<tsa_url>]",
+			Required: true,
+		},
+		&cli.DurationFlag{
+			Name:    "expiry",
+			Aliases: []string{"e"},
+			Usage:   "expire duration of the signature",
+		},
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "write signature to the file instead of stdout",
+		},
+		stapleRevocationFlag,
+	},
+	Action: runSignBlob,
+}
+
+func runSignBlob(ctx *cli.Context) error {
+	// initialize
+	args := ctx.Args()
+	if args.Len() != 1 {
+		return errors.New("missing blob file")
+	}
+	content, err := os.ReadFile(args.Get(0))
+	if err != nil {
+		return err
+	}
+	var opts notation.SignOptions
+	if expiry := ctx.Duration("expiry"); expiry != 0 {
+		opts.Expiry = time.Now().Add(expiry)
+	}
+
+	// sign blob
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	signer, opts, err := getSignerWithOptions(cfg, ctx.String("key"), opts)
+	if err != nil {
+		return err
+	}
+	if ctx.Bool(stapleRevocationFlag.Name) {
+		signer.RevocationFetcher = &cose.HTTPFetcher{}
+	}
+	sig, err := signer.SignBlob(ctx.Context, content, opts)
+	if err != nil {
+		return err
+	}
+
+	// write response
+	if path := ctx.String("output"); path != "" {
+		return os.WriteFile(path, sig, 0644)
+	}
+	_, err = os.Stdout.Write(sig)
+	return err
+}
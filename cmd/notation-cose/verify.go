@@ -3,11 +3,15 @@ package main
 import (
 	"crypto/x509"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/microsoft/notation-cose/internal/config"
 	"github.com/microsoft/notation-cose/pkg/cose"
 	"github.com/microsoft/notation-cose/pkg/protocol"
+	"github.com/microsoft/notation-cose/pkg/trustpolicy"
 	"github.com/notaryproject/notation-go"
 	"github.com/notaryproject/notation-go/crypto/cryptoutil"
 	"github.com/urfave/cli/v2"
@@ -16,33 +20,114 @@ import (
 var verifyCommand = &cli.Command{
 	Name:      "verify",
 	Usage:     "Verify OCI artifacts against COSE signatures",
-	ArgsUsage: "<reference>",
-	Action:    runVerify,
+	ArgsUsage: "[<request>]",
+	Flags: append([]cli.Flag{
+		requestFileFlag,
+		&cli.BoolFlag{
+			Name:  "result",
+			Usage: "print the verification result in JSON instead of the descriptor",
+		},
+		revocationFlag,
+		verifyAtFlag,
+		&cli.StringFlag{
+			Name:  "trust-policy",
+			Usage: "path to the trust policy document selecting the trust stores by the artifact reference",
+		},
+		&cli.StringFlag{
+			Name:  "reference",
+			Usage: "reference of the verified artifact in the format of `<registry>/<repository>[:<tag>|@<digest>]`, required by the trust policy",
+		},
+		&cli.StringSliceFlag{
+			Name:  "trust-store",
+			Usage: "trust store referenced by the trust policy or the threshold policy in the format of `name=path`, in addition to the trust stores in the configuration",
+		},
+		&cli.IntFlag{
+			Name:  "threshold",
+			Usage: "minimum number of the threshold trust stores satisfied by the signatures of a COSE_Sign envelope",
+		},
+		&cli.StringSliceFlag{
+			Name:  "threshold-trust-store",
+			Usage: "name of the trust store of an independent signing party counted toward the threshold",
+		},
+	}, countersignatureFlags...),
+	Action: runVerify,
 }
 
 func runVerify(ctx *cli.Context) error {
-	// initialize
-	args := ctx.Args()
-	if args.Len() != 1 {
-		return errors.New("missing request")
-	}
-
 	// parse request
-	var req protocol.VerifyRequest
-	if err := json.Unmarshal([]byte(args.Get(0)), &req); err != nil {
+	r, err := openRequest(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	req, err := protocol.DecodeVerifyRequest(r)
+	if err != nil {
+		return err
+	}
+	if err := req.Validate(); err != nil {
 		return err
 	}
 
 	// verify signature
-	verifier, err := getVerifier(req.KMSProfile.ID)
+	cfg, err := loadConfig(ctx)
 	if err != nil {
 		return err
 	}
-	desc, err := verifier.Verify(ctx.Context, req.Signature, req.VerifyOptions)
+	verifier, err := getVerifier(cfg, req.KMSProfile.ID, ctx.String(revocationFlag.Name))
 	if err != nil {
 		return err
 	}
-	out, err := json.Marshal(desc)
+	if path := ctx.String("trust-policy"); path != "" {
+		if err := setTrustPolicy(verifier, cfg, path, ctx.StringSlice("trust-store")); err != nil {
+			return err
+		}
+	}
+	if err := setVerifyAt(ctx, verifier); err != nil {
+		return err
+	}
+	if err := setCountersignaturePolicy(ctx, verifier); err != nil {
+		return err
+	}
+	if stores := ctx.StringSlice("threshold-trust-store"); len(stores) > 0 {
+		if verifier.TrustStores == nil {
+			if err := setTrustStores(verifier, cfg, ctx.StringSlice("trust-store")); err != nil {
+				return err
+			}
+		}
+		threshold := ctx.Int("threshold")
+		if threshold == 0 {
+			threshold = len(stores)
+		}
+		verifier.Threshold = &cose.ThresholdPolicy{
+			TrustStores: stores,
+			Threshold:   threshold,
+		}
+	}
+	opts := cose.VerifyOptions{
+		VerifyOptions:     req.VerifyOptions,
+		ArtifactReference: ctx.String("reference"),
+	}
+	var result interface{}
+	var desc notation.Descriptor
+	if cose.IsMultiSigner(req.Signature) {
+		multiResult, err := verifier.VerifyMultiWithOptions(ctx.Context, req.Signature, opts)
+		if err != nil {
+			return err
+		}
+		result, desc = multiResult, multiResult.Descriptor
+	} else {
+		sigResult, err := verifier.VerifyWithOptions(ctx.Context, req.Signature, opts)
+		if err != nil {
+			return err
+		}
+		result, desc = sigResult, sigResult.Descriptor
+	}
+	var out []byte
+	if ctx.Bool("result") {
+		out, err = json.Marshal(result)
+	} else {
+		out, err = json.Marshal(desc)
+	}
 	if err != nil {
 		return err
 	}
@@ -52,16 +137,101 @@ func runVerify(ctx *cli.Context) error {
 	return err
 }
 
-func getVerifier(certPath string) (notation.Verifier, error) {
-	bundledCerts, err := cryptoutil.ReadCertificateFile(certPath)
+// revocationFlag configures the revocation checking mode of the verifier.
+var revocationFlag = &cli.StringFlag{
+	Name:  "revocation",
+	Usage: "revocation checking mode: off, soft-fail, or hard-fail",
+	Value: "off",
+}
+
+// verifyAtFlag configures the verifier to verify as of a historical time.
+var verifyAtFlag = &cli.StringFlag{
+	Name:  "at",
+	Usage: "verify whether the signature was valid at the specified time in RFC 3339, such as 2022-05-01T00:00:00Z",
+}
+
+// setVerifyAt configures the verifier to verify as of the time specified by
+// the flag if present.
+func setVerifyAt(ctx *cli.Context, verifier *cose.Verifier) error {
+	value := ctx.String(verifyAtFlag.Name)
+	if value == "" {
+		return nil
+	}
+	at, err := time.Parse(time.RFC3339, value)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("invalid verification time: %w", err)
+	}
+	verifier.VerifyAt = at
+	return nil
+}
+
+// getVerifier creates the verifier trusting the certificates in the trust store
+// of the configuration referenced by name, or in the certificate file.
+func getVerifier(cfg *config.Config, certID string, revocationMode string) (*cose.Verifier, error) {
+	certPaths, ok := cfg.TrustStores[certID]
+	if !ok && certID != "" {
+		certPaths = []string{certID}
 	}
 	roots := x509.NewCertPool()
-	for _, cert := range bundledCerts {
-		roots.AddCert(cert)
+	for _, certPath := range certPaths {
+		bundledCerts, err := cryptoutil.ReadCertificateFile(certPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, cert := range bundledCerts {
+			roots.AddCert(cert)
+		}
 	}
 	verifier := cose.NewVerifier()
 	verifier.VerifyOptions.Roots = roots
+	verifier.AllowedClockSkew = cfg.VerifyOptions.AllowedClockSkew()
+	verifier.TimestampQuorum = cfg.VerifyOptions.TimestampQuorum
+	switch revocationMode {
+	case "", "off":
+	case "soft-fail":
+		verifier.Revocation = cose.NewRevocationChecker(nil, cose.RevocationModeSoftFail)
+	case "hard-fail":
+		verifier.Revocation = cose.NewRevocationChecker(nil, cose.RevocationModeHardFail)
+	default:
+		return nil, fmt.Errorf("unknown revocation mode: %s", revocationMode)
+	}
 	return verifier, nil
 }
+
+// setTrustPolicy loads the trust policy and the trust stores into the
+// verifier.
+func setTrustPolicy(verifier *cose.Verifier, cfg *config.Config, policyPath string, trustStores []string) error {
+	policy, err := trustpolicy.Load(policyPath)
+	if err != nil {
+		return err
+	}
+	verifier.TrustPolicy = policy
+	return setTrustStores(verifier, cfg, trustStores)
+}
+
+// setTrustStores loads the trust stores of the configuration, and the trust
+// stores in the format of `name=path` into the verifier.
+func setTrustStores(verifier *cose.Verifier, cfg *config.Config, trustStores []string) error {
+	verifier.TrustStores = make(map[string][]*x509.Certificate)
+	for name, paths := range cfg.TrustStores {
+		for _, path := range paths {
+			certs, err := cryptoutil.ReadCertificateFile(path)
+			if err != nil {
+				return err
+			}
+			verifier.TrustStores[name] = append(verifier.TrustStores[name], certs...)
+		}
+	}
+	for _, trustStore := range trustStores {
+		name, path, ok := strings.Cut(trustStore, "=")
+		if !ok {
+			return fmt.Errorf("invalid trust store: %s", trustStore)
+		}
+		certs, err := cryptoutil.ReadCertificateFile(path)
+		if err != nil {
+			return err
+		}
+		verifier.TrustStores[name] = append(verifier.TrustStores[name], certs...)
+	}
+	return nil
+}
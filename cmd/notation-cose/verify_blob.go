@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/notaryproject/notation-go"
+	"github.com/urfave/cli/v2"
+)
+
+var verifyBlobCommand = &cli.Command{
+	Name:      "verify-blob",
+	Usage:     "Verify arbitrary blobs against COSE signatures with detached payload",
+	ArgsUsage: "<file>",
+	Flags: append([]cli.Flag{
+		&cli.StringFlag{
+			Name:     "cert",
+			Aliases:  []string{"c"},
+			Usage:    "trust store in the configuration, or path to the trusted certificates",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "signature",
+			Aliases:  []string{"s"},
+			Usage:    "path to the signature",
+			Required: true,
+		},
+		revocationFlag,
+		verifyAtFlag,
+	}, countersignatureFlags...),
+	Action: runVerifyBlob,
+}
+
+func runVerifyBlob(ctx *cli.Context) error {
+	// initialize
+	args := ctx.Args()
+	if args.Len() != 1 {
+		return errors.New("missing blob file")
+	}
+	content, err := os.ReadFile(args.Get(0))
+	if err != nil {
+		return err
+	}
+	sig, err := os.ReadFile(ctx.String("signature"))
+	if err != nil {
+		return err
+	}
+
+	// verify signature
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	verifier, err := getVerifier(cfg, ctx.String("cert"), ctx.String(revocationFlag.Name))
+	if err != nil {
+		return err
+	}
+	if err := setVerifyAt(ctx, verifier); err != nil {
+		return err
+	}
+	if err := setCountersignaturePolicy(ctx, verifier); err != nil {
+		return err
+	}
+	return verifier.VerifyBlob(ctx.Context, sig, content, notation.VerifyOptions{})
+}
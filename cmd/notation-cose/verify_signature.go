@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/x509"
+
+	"github.com/microsoft/notation-cose/pkg/cose"
+	"github.com/microsoft/notation-cose/pkg/protocol"
+	"github.com/microsoft/notation-cose/pkg/trustpolicy"
+	"github.com/urfave/cli/v2"
+)
+
+var verifySignatureCommand = pluginCommand(
+	protocol.CommandVerifySignature,
+	"Verify the signer identity and the revocation status of a verified signature",
+	runVerifySignature,
+)
+
+func runVerifySignature(ctx *cli.Context) (interface{}, error) {
+	// parse request
+	var req protocol.VerifySignatureRequest
+	if err := readPluginRequest(ctx, &req); err != nil {
+		return nil, err
+	}
+	certs := make([]*x509.Certificate, 0, len(req.Signature.CertificateChain))
+	for _, certBytes := range req.Signature.CertificateChain {
+		cert, err := x509.ParseCertificate(certBytes)
+		if err != nil {
+			return nil, validationError("invalid certificate chain: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	// perform the requested verification
+	resp := &protocol.VerifySignatureResponse{
+		VerificationResults: make(map[protocol.Capability]*protocol.VerificationResult),
+		ProcessedAttributes: []interface{}{},
+	}
+	for _, capability := range req.TrustPolicy.SignatureVerification {
+		var err error
+		switch capability {
+		case protocol.CapabilityTrustedIdentityVerifier:
+			policy := &trustpolicy.Policy{
+				Name:              "plugin",
+				TrustedIdentities: req.TrustPolicy.TrustedIdentities,
+			}
+			err = policy.VerifyIdentity(certs[0])
+		case protocol.CapabilityRevocationCheckVerifier:
+			// the request carries no stapled revocation data
+			checker := cose.NewRevocationChecker(nil, cose.RevocationModeHardFail)
+			err = checker.Check(ctx.Context, certs)
+		}
+		result := &protocol.VerificationResult{
+			Success: err == nil,
+		}
+		if err != nil {
+			result.Reason = err.Error()
+		}
+		resp.VerificationResults[capability] = result
+	}
+	return resp, nil
+}
@@ -3,16 +3,18 @@ module github.com/microsoft/notation-cose
 go 1.18
 
 require (
+	github.com/fxamacker/cbor/v2 v2.4.0
 	github.com/notaryproject/notation-go v0.8.0-alpha.1
 	github.com/opencontainers/go-digest v1.0.0
 	github.com/oras-project/artifacts-spec v1.0.0-rc.1
 	github.com/urfave/cli/v2 v2.5.0
 	github.com/veraison/go-cose v0.0.0-20220425074922-8cef769ef52c
+	golang.org/x/crypto v0.11.0
+	software.sslmate.com/src/go-pkcs12 v0.2.1
 )
 
 require (
 	github.com/cpuguy83/go-md2man/v2 v2.0.1 // indirect
-	github.com/fxamacker/cbor/v2 v2.4.0 // indirect
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
 	github.com/x448/float16 v0.8.4 // indirect
 )
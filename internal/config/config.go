@@ -0,0 +1,208 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// EnvConfigPath is the environment variable overriding the path to the
+// configuration file.
+const EnvConfigPath = "NOTATION_COSE_CONFIG"
+
+// Config is the configuration file of the plugin.
+type Config struct {
+	// Keys contains the named key profiles, which can be referenced by name
+	// in place of the key descriptor.
+	Keys map[string]*Key `json:"keys,omitempty"`
+
+	// TrustStores maps the names of the trust stores to the paths of the PEM
+	// encoded trusted certificates.
+	TrustStores map[string][]string `json:"trustStores,omitempty"`
+
+	// TSAEndpoints lists the default timestamping authorities for the keys
+	// without TSA endpoints.
+	TSAEndpoints []string `json:"tsaEndpoints,omitempty"`
+
+	// SignOptions contains the default signing options.
+	SignOptions SignOptions `json:"signOptions"`
+
+	// VerifyOptions contains the default verification options.
+	VerifyOptions VerifyOptions `json:"verifyOptions"`
+}
+
+// SignOptions contains the default signing options.
+type SignOptions struct {
+	// ExpiryDurationInSeconds is the default expiry duration of the
+	// signatures. If zero, the signatures do not expire.
+	ExpiryDurationInSeconds int64 `json:"expiryDurationInSeconds,omitempty"`
+
+	// StapleRevocation configures the signer to staple the revocation data of
+	// the certificate chain in the signature.
+	StapleRevocation bool `json:"stapleRevocation,omitempty"`
+
+	// TSARetries is the number of retries of a TSA on transport failures and
+	// server errors before failing over to the next TSA.
+	TSARetries int `json:"tsaRetries,omitempty"`
+
+	// TSAQuorum is the number of timestamps collected from distinct TSAs. If
+	// not greater than one, the timestamp of the first TSA succeeded is used.
+	TSAQuorum int `json:"tsaQuorum,omitempty"`
+}
+
+// Expiry returns the default expiry duration of the signatures.
+func (o SignOptions) Expiry() time.Duration {
+	return time.Duration(o.ExpiryDurationInSeconds) * time.Second
+}
+
+// VerifyOptions contains the default verification options.
+type VerifyOptions struct {
+	// AllowedClockSkewInSeconds tolerates the drift between the clocks of the
+	// signers and the verifier when checking the signing time and the expiry
+	// of the signatures.
+	AllowedClockSkewInSeconds int64 `json:"allowedClockSkewInSeconds,omitempty"`
+
+	// TimestampQuorum is the number of valid timestamps of distinct TSAs
+	// required when the timestamp is verified.
+	TimestampQuorum int `json:"timestampQuorum,omitempty"`
+}
+
+// AllowedClockSkew returns the tolerated clock skew.
+func (o VerifyOptions) AllowedClockSkew() time.Duration {
+	return time.Duration(o.AllowedClockSkewInSeconds) * time.Second
+}
+
+// DefaultPath returns the path to the configuration file, which is
+// `$XDG_CONFIG_HOME/notation-cose/config.json` on Linux unless overridden by
+// the environment variable `NOTATION_COSE_CONFIG`.
+func DefaultPath() (string, error) {
+	if path := os.Getenv(EnvConfigPath); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "notation-cose", "config.json"), nil
+}
+
+// Load reads and validates the configuration file at the path.
+// Relative paths in the configuration are resolved against the directory of
+// the configuration file.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	config.resolvePaths(filepath.Dir(path))
+	return &config, nil
+}
+
+// LoadDefault reads the configuration file at the default path. An empty
+// configuration is returned if the file does not exist.
+func LoadDefault() (*Config, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return &Config{}, nil
+	}
+	config, err := Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	return config, err
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	for name, key := range c.Keys {
+		if name == "" {
+			return errors.New("invalid config: key profile with empty name")
+		}
+		if key == nil {
+			return fmt.Errorf("invalid config: key profile %q: missing key descriptor", name)
+		}
+		if err := key.Validate(); err != nil {
+			return fmt.Errorf("invalid config: key profile %q: %w", name, err)
+		}
+	}
+	for name, paths := range c.TrustStores {
+		if name == "" {
+			return errors.New("invalid config: trust store with empty name")
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("invalid config: trust store %q: no certificate", name)
+		}
+	}
+	if err := validateEndpoints(c.TSAEndpoints); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	if c.SignOptions.ExpiryDurationInSeconds < 0 {
+		return fmt.Errorf("invalid config: negative expiry duration: %d", c.SignOptions.ExpiryDurationInSeconds)
+	}
+	if c.SignOptions.TSARetries < 0 {
+		return fmt.Errorf("invalid config: negative TSA retries: %d", c.SignOptions.TSARetries)
+	}
+	if c.SignOptions.TSAQuorum < 0 {
+		return fmt.Errorf("invalid config: negative TSA quorum: %d", c.SignOptions.TSAQuorum)
+	}
+	if c.VerifyOptions.TimestampQuorum < 0 {
+		return fmt.Errorf("invalid config: negative timestamp quorum: %d", c.VerifyOptions.TimestampQuorum)
+	}
+	if c.VerifyOptions.AllowedClockSkewInSeconds < 0 {
+		return fmt.Errorf("invalid config: negative clock skew: %d", c.VerifyOptions.AllowedClockSkewInSeconds)
+	}
+	return nil
+}
+
+// ResolveKey resolves the key by the name of the key profile, or parses the
+// key descriptor if no profile is found. The default TSA endpoints are applied
+// to the keys without TSA endpoints.
+func (c *Config) ResolveKey(id string) (*Key, error) {
+	key, ok := c.Keys[id]
+	if ok {
+		profile := *key
+		key = &profile
+	} else {
+		var err error
+		if key, err = ParseKey(id); err != nil {
+			return nil, err
+		}
+	}
+	if len(key.TSAEndpoints) == 0 {
+		key.TSAEndpoints = c.TSAEndpoints
+	}
+	return key, nil
+}
+
+// resolvePaths resolves the relative paths against the directory.
+func (c *Config) resolvePaths(dir string) {
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+	for _, key := range c.Keys {
+		key.KeyFile = resolve(key.KeyFile)
+		key.CertificateChainFile = resolve(key.CertificateChainFile)
+		key.PKCS12File = resolve(key.PKCS12File)
+		if key.Passphrase != nil {
+			key.Passphrase.File = resolve(key.Passphrase.File)
+		}
+	}
+	for _, paths := range c.TrustStores {
+		for i, path := range paths {
+			paths[i] = resolve(path)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	data := []byte(`{
+		"keys": {
+			"release": {
+				"keyFile": "keys/release.key",
+				"certificateChainFile": "/etc/keys/release.crt",
+				"passphrase": {"command": ["pass", "show", "release"]},
+				"algorithm": "ES384"
+			},
+			"build": {
+				"keyFile": "build.key",
+				"certificateChainFile": "build.crt",
+				"tsaEndpoints": ["http://tsa.build.example.com"]
+			},
+			"hsm": {
+				"certificateChainFile": "hsm.crt",
+				"externalSigner": {"url": "http://localhost:8080/sign", "keyId": "key-1"}
+			}
+		},
+		"trustStores": {
+			"wabbit-networks": ["certs/wabbit-networks.crt"]
+		},
+		"tsaEndpoints": ["http://tsa.example.com:8080"],
+		"signOptions": {
+			"expiryDurationInSeconds": 3600,
+			"stapleRevocation": true,
+			"tsaRetries": 2,
+			"tsaQuorum": 2
+		},
+		"verifyOptions": {
+			"allowedClockSkewInSeconds": 30,
+			"timestampQuorum": 2
+		}
+	}`)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got, want := cfg.SignOptions.Expiry(), time.Hour; got != want {
+		t.Errorf("SignOptions.Expiry() = %v, want %v", got, want)
+	}
+	if !cfg.SignOptions.StapleRevocation {
+		t.Error("SignOptions.StapleRevocation = false, want true")
+	}
+	if got, want := cfg.VerifyOptions.AllowedClockSkew(), 30*time.Second; got != want {
+		t.Errorf("VerifyOptions.AllowedClockSkew() = %v, want %v", got, want)
+	}
+	if cfg.SignOptions.TSARetries != 2 || cfg.SignOptions.TSAQuorum != 2 || cfg.VerifyOptions.TimestampQuorum != 2 {
+		t.Errorf("TSA options = %+v, %+v, want retries and quorums of 2", cfg.SignOptions, cfg.VerifyOptions)
+	}
+	if got, want := cfg.TrustStores["wabbit-networks"], []string{filepath.Join(dir, "certs/wabbit-networks.crt")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("TrustStores[wabbit-networks] = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		id   string
+		want *Key
+	}{
+		{
+			id: "release",
+			want: &Key{
+				KeyFile:              filepath.Join(dir, "keys/release.key"),
+				CertificateChainFile: "/etc/keys/release.crt",
+				Passphrase:           &Passphrase{Command: []string{"pass", "show", "release"}},
+				TSAEndpoints:         []string{"http://tsa.example.com:8080"},
+				Algorithm:            "ES384",
+			},
+		},
+		{
+			id: "build",
+			want: &Key{
+				KeyFile:              filepath.Join(dir, "build.key"),
+				CertificateChainFile: filepath.Join(dir, "build.crt"),
+				TSAEndpoints:         []string{"http://tsa.build.example.com"},
+			},
+		},
+		{
+			id: "hsm",
+			want: &Key{
+				CertificateChainFile: filepath.Join(dir, "hsm.crt"),
+				ExternalSigner: &ExternalSigner{
+					URL:   "http://localhost:8080/sign",
+					KeyID: "key-1",
+				},
+				TSAEndpoints: []string{"http://tsa.example.com:8080"},
+			},
+		},
+		{
+			id: "key.pem:cert.pem",
+			want: &Key{
+				KeyFile:              "key.pem",
+				CertificateChainFile: "cert.pem",
+				TSAEndpoints:         []string{"http://tsa.example.com:8080"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got, err := cfg.ResolveKey(tt.id)
+		if err != nil {
+			t.Fatalf("ResolveKey(%q) error = %v", tt.id, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ResolveKey(%q) = %+v, want %+v", tt.id, got, tt.want)
+		}
+	}
+
+	// resolved keys do not alias the profiles
+	key, _ := cfg.ResolveKey("release")
+	key.Algorithm = "PS256"
+	if cfg.Keys["release"].Algorithm != "ES384" {
+		t.Error("ResolveKey() modifies the key profile")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for name, cfg := range map[string]*Config{
+		"invalid key": {
+			Keys: map[string]*Key{"release": {KeyFile: "key.pem"}},
+		},
+		"missing key": {
+			Keys: map[string]*Key{"release": nil},
+		},
+		"empty trust store": {
+			TrustStores: map[string][]string{"wabbit-networks": nil},
+		},
+		"invalid TSA endpoint": {
+			TSAEndpoints: []string{"tsa.example.com"},
+		},
+		"negative expiry": {
+			SignOptions: SignOptions{ExpiryDurationInSeconds: -1},
+		},
+		"negative TSA retries": {
+			SignOptions: SignOptions{TSARetries: -1},
+		},
+		"negative timestamp quorum": {
+			VerifyOptions: VerifyOptions{TimestampQuorum: -1},
+		},
+		"negative clock skew": {
+			VerifyOptions: VerifyOptions{AllowedClockSkewInSeconds: -1},
+		},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Validate() %s: error = nil, want error", name)
+		}
+	}
+}
+
+func TestLoadDefault(t *testing.T) {
+	t.Setenv(EnvConfigPath, filepath.Join(t.TempDir(), "config.json"))
+	cfg, err := LoadDefault()
+	if err != nil {
+		t.Fatalf("LoadDefault() error = %v", err)
+	}
+	if !reflect.DeepEqual(cfg, &Config{}) {
+		t.Errorf("LoadDefault() = %+v, want empty config", cfg)
+	}
+}
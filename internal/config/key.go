@@ -0,0 +1,313 @@
+// Package config provides the configuration of the notation-cose plugin.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/microsoft/notation-cose/pkg/cose"
+	gocose "github.com/veraison/go-cose"
+)
+
+// Key describes a signing key, its certificate chain, and the signing
+// options bound to the key.
+type Key struct {
+	// KeyFile is the path to the PEM encoded private key, which can be
+	// encrypted in PKCS#8.
+	KeyFile string `json:"keyFile,omitempty"`
+
+	// CertificateChainFile is the path to the PEM encoded certificate chain
+	// starting from the signing certificate.
+	CertificateChainFile string `json:"certificateChainFile,omitempty"`
+
+	// PKCS12File is the path to the PKCS#12 / PFX bundle containing the
+	// private key and the certificate chain, in place of the key file and the
+	// certificate chain file.
+	PKCS12File string `json:"pkcs12File,omitempty"`
+
+	// Passphrase is the source of the passphrase decrypting the private key.
+	Passphrase *Passphrase `json:"passphrase,omitempty"`
+
+	// ExternalSigner is the backend signing on behalf of the key of the
+	// certificate chain, in place of the key file.
+	ExternalSigner *ExternalSigner `json:"externalSigner,omitempty"`
+
+	// TSAEndpoints lists the URLs of the timestamping authorities, which are
+	// tried in order until a timestamp is obtained.
+	// If not present, the signature is not timestamped.
+	TSAEndpoints []string `json:"tsaEndpoints,omitempty"`
+
+	// Algorithm overrides the signing algorithm, such as `PS384`.
+	// If not present, the algorithm is picked up by the type and the size of
+	// the key.
+	Algorithm string `json:"algorithm,omitempty"`
+
+	// Headers contains the additional header parameters of the signature.
+	Headers *HeaderOptions `json:"headers,omitempty"`
+}
+
+// Passphrase is the source of the passphrase of an encrypted key. Exactly one
+// source is specified.
+type Passphrase struct {
+	// Env is the name of the environment variable containing the passphrase.
+	Env string `json:"env,omitempty"`
+
+	// File is the path to the file containing the passphrase. The trailing
+	// newline is trimmed.
+	File string `json:"file,omitempty"`
+
+	// Command is the command with arguments printing the passphrase to the
+	// standard output. The trailing newline is trimmed.
+	Command []string `json:"command,omitempty"`
+}
+
+// ExternalSigner is a backend signing on behalf of the plugin so that the key
+// never enters the plugin process. Exactly one backend is specified.
+type ExternalSigner struct {
+	// URL is the endpoint of the signing service.
+	URL string `json:"url,omitempty"`
+
+	// Command is the signing command with arguments.
+	Command []string `json:"command,omitempty"`
+
+	// KeyID identifies the key in the backend if present.
+	KeyID string `json:"keyId,omitempty"`
+}
+
+// HeaderOptions contains the additional header parameters of the signature.
+type HeaderOptions struct {
+	// KeyID is the key identifier set in the `kid` protected header parameter.
+	KeyID string `json:"kid,omitempty"`
+
+	// Protected contains the additional protected header parameters with
+	// text labels.
+	Protected map[string]interface{} `json:"protected,omitempty"`
+
+	// Unprotected contains the additional unprotected header parameters with
+	// text labels.
+	Unprotected map[string]interface{} `json:"unprotected,omitempty"`
+
+	// Critical lists the labels of the parameters in Protected to be marked
+	// critical, which must be understood by the verifiers.
+	Critical []string `json:"critical,omitempty"`
+}
+
+// ParseKey parses the key descriptor in JSON, or in the legacy format of
+// `<key path>:<cert path>[:<timestamp url>]`.
+// The legacy format cannot represent paths with Windows drive letters.
+// Since the descriptor comes from the signing request, passphrase commands and
+// external signers are rejected, and are only allowed in the key profiles of
+// the config file.
+func ParseKey(id string) (*Key, error) {
+	if strings.HasPrefix(strings.TrimSpace(id), "{") {
+		var key Key
+		if err := json.Unmarshal([]byte(id), &key); err != nil {
+			return nil, fmt.Errorf("invalid key descriptor: %w", err)
+		}
+		if err := key.Validate(); err != nil {
+			return nil, err
+		}
+		if key.Passphrase != nil && len(key.Passphrase.Command) > 0 {
+			return nil, errors.New("invalid key descriptor: passphrase command is only allowed in the key profiles of the config file")
+		}
+		if key.ExternalSigner != nil {
+			return nil, errors.New("invalid key descriptor: external signer is only allowed in the key profiles of the config file")
+		}
+		return &key, nil
+	}
+
+	items := strings.SplitN(id, ":", 3)
+	if len(items) < 2 {
+		return nil, errors.New("missing signing key pair")
+	}
+	key := &Key{
+		KeyFile:              items[0],
+		CertificateChainFile: items[1],
+	}
+	if len(items) > 2 {
+		key.TSAEndpoints = []string{items[2]}
+	}
+	return key, nil
+}
+
+// Validate validates the key descriptor.
+func (k *Key) Validate() error {
+	switch {
+	case k.PKCS12File != "":
+		if k.KeyFile != "" || k.CertificateChainFile != "" || k.ExternalSigner != nil {
+			return errors.New("invalid key descriptor: PKCS#12 file is mutually exclusive with key file and external signer")
+		}
+	case k.ExternalSigner != nil:
+		if k.KeyFile != "" {
+			return errors.New("invalid key descriptor: key file and external signer are mutually exclusive")
+		}
+		if err := k.ExternalSigner.Validate(); err != nil {
+			return fmt.Errorf("invalid key descriptor: %w", err)
+		}
+	case k.KeyFile == "":
+		return errors.New("invalid key descriptor: missing key file")
+	}
+	if k.PKCS12File == "" && k.CertificateChainFile == "" {
+		return errors.New("invalid key descriptor: missing certificate chain file")
+	}
+	if k.Passphrase != nil {
+		if err := k.Passphrase.Validate(); err != nil {
+			return fmt.Errorf("invalid key descriptor: %w", err)
+		}
+	}
+	if err := validateEndpoints(k.TSAEndpoints); err != nil {
+		return fmt.Errorf("invalid key descriptor: %w", err)
+	}
+	if k.Algorithm != "" {
+		if _, err := cose.ParseAlgorithm(k.Algorithm); err != nil {
+			return fmt.Errorf("invalid key descriptor: %w", err)
+		}
+	}
+	if k.Headers != nil {
+		for _, label := range k.Headers.Critical {
+			if _, ok := k.Headers.Protected[label]; !ok {
+				return fmt.Errorf("invalid key descriptor: critical header %q not present in the protected headers", label)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate validates the passphrase source.
+func (p *Passphrase) Validate() error {
+	var n int
+	for _, present := range []bool{p.Env != "", p.File != "", len(p.Command) > 0} {
+		if present {
+			n++
+		}
+	}
+	if n != 1 {
+		return errors.New("passphrase: exactly one of env, file, and command is required")
+	}
+	return nil
+}
+
+// Read reads the passphrase from the source.
+func (p *Passphrase) Read() ([]byte, error) {
+	switch {
+	case p.Env != "":
+		passphrase, ok := os.LookupEnv(p.Env)
+		if !ok {
+			return nil, fmt.Errorf("passphrase: environment variable %s is not set", p.Env)
+		}
+		return []byte(passphrase), nil
+	case p.File != "":
+		passphrase, err := os.ReadFile(p.File)
+		if err != nil {
+			return nil, err
+		}
+		return trimNewline(passphrase), nil
+	case len(p.Command) > 0:
+		cmd := exec.Command(p.Command[0], p.Command[1:]...)
+		cmd.Stderr = os.Stderr
+		passphrase, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("passphrase: command %s: %w", p.Command[0], err)
+		}
+		return trimNewline(passphrase), nil
+	}
+	return nil, errors.New("passphrase: no source")
+}
+
+// trimNewline trims a single trailing newline.
+func trimNewline(data []byte) []byte {
+	data = bytes.TrimSuffix(data, []byte("\n"))
+	return bytes.TrimSuffix(data, []byte("\r"))
+}
+
+// Validate validates the external signer.
+func (s *ExternalSigner) Validate() error {
+	if (s.URL == "") == (len(s.Command) == 0) {
+		return errors.New("external signer: exactly one of url and command is required")
+	}
+	if s.URL != "" {
+		if u, err := url.Parse(s.URL); err != nil || !u.IsAbs() {
+			return fmt.Errorf("external signer: invalid url: %q", s.URL)
+		}
+	}
+	return nil
+}
+
+// validateEndpoints checks if the TSA endpoints are absolute URLs.
+func validateEndpoints(endpoints []string) error {
+	for _, endpoint := range endpoints {
+		if u, err := url.Parse(endpoint); err != nil || !u.IsAbs() {
+			return fmt.Errorf("invalid TSA endpoint: %q", endpoint)
+		}
+	}
+	return nil
+}
+
+// ProtectedHeaders returns the additional protected header parameters.
+func (o *HeaderOptions) ProtectedHeaders() gocose.ProtectedHeader {
+	if o == nil {
+		return nil
+	}
+	header := gocose.ProtectedHeader(headerMap(o.Protected))
+	if o.KeyID != "" {
+		header[gocose.HeaderLabelKeyID] = []byte(o.KeyID)
+	}
+	return header
+}
+
+// UnprotectedHeaders returns the additional unprotected header parameters.
+func (o *HeaderOptions) UnprotectedHeaders() gocose.UnprotectedHeader {
+	if o == nil {
+		return nil
+	}
+	return gocose.UnprotectedHeader(headerMap(o.Unprotected))
+}
+
+// CriticalHeaders returns the labels of the protected header parameters
+// marked critical.
+func (o *HeaderOptions) CriticalHeaders() []interface{} {
+	if o == nil || len(o.Critical) == 0 {
+		return nil
+	}
+	labels := make([]interface{}, 0, len(o.Critical))
+	for _, label := range o.Critical {
+		labels = append(labels, label)
+	}
+	return labels
+}
+
+// headerMap converts the header parameters decoded from JSON to a COSE header
+// map. Integral numbers are encoded as integers instead of floats.
+func headerMap(params map[string]interface{}) map[interface{}]interface{} {
+	header := make(map[interface{}]interface{}, len(params))
+	for label, value := range params {
+		header[label] = headerValue(value)
+	}
+	return header
+}
+
+// headerValue converts a header parameter value decoded from JSON.
+func headerValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case float64:
+		if value == math.Trunc(value) && math.Abs(value) < math.MaxInt64 {
+			return int64(value)
+		}
+	case []interface{}:
+		values := make([]interface{}, 0, len(value))
+		for _, v := range value {
+			values = append(values, headerValue(v))
+		}
+		return values
+	case map[string]interface{}:
+		return headerMap(value)
+	}
+	return value
+}
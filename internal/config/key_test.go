@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	gocose "github.com/veraison/go-cose"
+)
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want *Key
+	}{
+		{
+			name: "legacy",
+			id:   "key.pem:cert.pem:http://timestamp.example.com:8080/tsa",
+			want: &Key{
+				KeyFile:              "key.pem",
+				CertificateChainFile: "cert.pem",
+				TSAEndpoints:         []string{"http://timestamp.example.com:8080/tsa"},
+			},
+		},
+		{
+			name: "descriptor",
+			id:   `{"keyFile":"C:\\keys\\key.pem","certificateChainFile":"C:\\keys\\cert.pem","tsaEndpoints":["http://tsa1.example.com:8080","http://tsa2.example.com"],"algorithm":"PS384"}`,
+			want: &Key{
+				KeyFile:              `C:\keys\key.pem`,
+				CertificateChainFile: `C:\keys\cert.pem`,
+				TSAEndpoints:         []string{"http://tsa1.example.com:8080", "http://tsa2.example.com"},
+				Algorithm:            "PS384",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKey(tt.id)
+			if err != nil {
+				t.Fatalf("ParseKey() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseKey() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseKeyErrors(t *testing.T) {
+	for _, id := range []string{
+		"key.pem",
+		`{"keyFile":"key.pem"}`,
+		`{"keyFile":"key.pem","certificateChainFile":"cert.pem","algorithm":"RS256"}`,
+		`{"keyFile":"key.pem","certificateChainFile":"cert.pem","tsaEndpoints":["tsa.example.com"]}`,
+		`{"keyFile":`,
+		`{"certificateChainFile":"cert.pem","externalSigner":{}}`,
+		`{"keyFile":"key.pem","certificateChainFile":"cert.pem","externalSigner":{"command":["sign"]}}`,
+		`{"externalSigner":{"command":["sign"]}}`,
+		`{"keyFile":"key.pem","certificateChainFile":"cert.pem","passphrase":{"command":["pass","show","key"]}}`,
+		`{"certificateChainFile":"cert.pem","externalSigner":{"url":"http://localhost:8080/sign","keyId":"key-1"}}`,
+		`{"certificateChainFile":"cert.pem","externalSigner":{"command":["sign"]}}`,
+		`{"keyFile":"key.pem","certificateChainFile":"cert.pem","headers":{"critical":["build"]}}`,
+	} {
+		if _, err := ParseKey(id); err == nil {
+			t.Errorf("ParseKey(%s) error = nil, want error", id)
+		}
+	}
+}
+
+func TestHeaderOptions(t *testing.T) {
+	key, err := ParseKey(`{"keyFile":"key.pem","certificateChainFile":"cert.pem","headers":{"kid":"key-1","protected":{"build":42,"ratio":0.5},"unprotected":{"note":"hello"},"critical":["build"]}}`)
+	if err != nil {
+		t.Fatalf("ParseKey() error = %v", err)
+	}
+	wantProtected := gocose.ProtectedHeader{
+		gocose.HeaderLabelKeyID: []byte("key-1"),
+		"build":                 int64(42),
+		"ratio":                 0.5,
+	}
+	if got := key.Headers.ProtectedHeaders(); !reflect.DeepEqual(got, wantProtected) {
+		t.Errorf("ProtectedHeaders() = %v, want %v", got, wantProtected)
+	}
+	wantUnprotected := gocose.UnprotectedHeader{
+		"note": "hello",
+	}
+	if got := key.Headers.UnprotectedHeaders(); !reflect.DeepEqual(got, wantUnprotected) {
+		t.Errorf("UnprotectedHeaders() = %v, want %v", got, wantUnprotected)
+	}
+	if got, want := key.Headers.CriticalHeaders(), []interface{}{"build"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("CriticalHeaders() = %v, want %v", got, want)
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "passphrase")
+	if err := os.WriteFile(path, []byte("secret\n"), 0600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	t.Setenv("NOTATION_COSE_TEST_PASSPHRASE", "secret")
+
+	for name, p := range map[string]*Passphrase{
+		"env":  {Env: "NOTATION_COSE_TEST_PASSPHRASE"},
+		"file": {File: path},
+	} {
+		if err := p.Validate(); err != nil {
+			t.Fatalf("Validate() %s: error = %v", name, err)
+		}
+		got, err := p.Read()
+		if err != nil {
+			t.Fatalf("Read() %s: error = %v", name, err)
+		}
+		if string(got) != "secret" {
+			t.Errorf("Read() %s: = %q, want %q", name, got, "secret")
+		}
+	}
+
+	if err := (&Passphrase{Env: "A", File: path}).Validate(); err == nil {
+		t.Error("Validate() error = nil, want error for multiple sources")
+	}
+}
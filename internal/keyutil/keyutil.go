@@ -0,0 +1,113 @@
+// Package keyutil parses signing keys and certificate chains at rest,
+// including the keys protected by a passphrase.
+package keyutil
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// ErrIncorrectPassphrase indicates that the key cannot be decrypted with the
+// passphrase.
+var ErrIncorrectPassphrase = errors.New("incorrect passphrase")
+
+// PassphraseFunc returns the passphrase of an encrypted key. It is called only
+// if the key is encrypted.
+type PassphraseFunc func() ([]byte, error)
+
+// ParsePrivateKeyPEM parses the first private key in the PEM data. The key can
+// be an unencrypted PKCS#1, SEC 1, or PKCS#8 key, or a PKCS#8 key encrypted by
+// PBES2.
+func ParsePrivateKeyPEM(data []byte, passphrase PassphraseFunc) (crypto.Signer, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no private key found")
+		}
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			return toSigner(key)
+		case "RSA PRIVATE KEY":
+			if _, ok := block.Headers["Proc-Type"]; ok {
+				return nil, errors.New("legacy PEM encryption is not supported: convert the key to encrypted PKCS#8")
+			}
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			if _, ok := block.Headers["Proc-Type"]; ok {
+				return nil, errors.New("legacy PEM encryption is not supported: convert the key to encrypted PKCS#8")
+			}
+			return x509.ParseECPrivateKey(block.Bytes)
+		case "ENCRYPTED PRIVATE KEY":
+			if passphrase == nil {
+				return nil, errors.New("missing passphrase of the encrypted private key")
+			}
+			password, err := passphrase()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read passphrase: %w", err)
+			}
+			der, err := decryptPKCS8(block.Bytes, password)
+			if err != nil {
+				return nil, err
+			}
+			key, err := x509.ParsePKCS8PrivateKey(der)
+			if err != nil {
+				return nil, ErrIncorrectPassphrase
+			}
+			return toSigner(key)
+		}
+	}
+}
+
+// ParsePKCS12 parses the private key and the certificate chain starting from
+// the certificate of the key in the PKCS#12 / PFX bundle. An empty passphrase
+// is used if no passphrase is provided.
+func ParsePKCS12(data []byte, passphrase PassphraseFunc) (crypto.Signer, []*x509.Certificate, error) {
+	var password []byte
+	if passphrase != nil {
+		var err error
+		if password, err = passphrase(); err != nil {
+			return nil, nil, fmt.Errorf("failed to read passphrase: %w", err)
+		}
+	}
+	key, cert, caCerts, err := pkcs12.DecodeChain(data, string(password))
+	if err != nil {
+		if errors.Is(err, pkcs12.ErrIncorrectPassword) {
+			return nil, nil, ErrIncorrectPassphrase
+		}
+		return nil, nil, err
+	}
+	signer, err := toSigner(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return signer, append([]*x509.Certificate{cert}, caCerts...), nil
+}
+
+// CheckKeyPair checks if the private key matches the public key of the
+// certificate.
+func CheckKeyPair(key crypto.Signer, cert *x509.Certificate) error {
+	pub, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(cert.PublicKey) {
+		return errors.New("private key does not match the public key of the certificate")
+	}
+	return nil
+}
+
+// toSigner converts the parsed private key to a signer.
+func toSigner(key interface{}) (crypto.Signer, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key: %T", key)
+	}
+	return signer, nil
+}
@@ -0,0 +1,140 @@
+package keyutil
+
+import (
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/notaryproject/notation-go/crypto/cryptoutil"
+)
+
+// passphrase returns a passphrase function of the password.
+func passphrase(password string) PassphraseFunc {
+	return func() ([]byte, error) {
+		return []byte(password), nil
+	}
+}
+
+func TestParsePrivateKeyPEM(t *testing.T) {
+	certs, err := cryptoutil.ReadCertificateFile("testdata/cert.pem")
+	if err != nil {
+		t.Fatalf("ReadCertificateFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		passphrase PassphraseFunc
+	}{
+		{"unencrypted", "testdata/key.pem", nil},
+		{"encrypted", "testdata/key.enc.pem", passphrase("secret")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile(tt.path)
+			if err != nil {
+				t.Fatalf("os.ReadFile() error = %v", err)
+			}
+			key, err := ParsePrivateKeyPEM(data, tt.passphrase)
+			if err != nil {
+				t.Fatalf("ParsePrivateKeyPEM() error = %v", err)
+			}
+			if err := CheckKeyPair(key, certs[0]); err != nil {
+				t.Errorf("CheckKeyPair() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestParsePrivateKeyPEMErrors(t *testing.T) {
+	data, err := os.ReadFile("testdata/key.enc.pem")
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if _, err := ParsePrivateKeyPEM(data, nil); err == nil {
+		t.Error("ParsePrivateKeyPEM() error = nil, want error for missing passphrase")
+	}
+	if _, err := ParsePrivateKeyPEM(data, passphrase("wrong")); !errors.Is(err, ErrIncorrectPassphrase) {
+		t.Errorf("ParsePrivateKeyPEM() error = %v, want %v", err, ErrIncorrectPassphrase)
+	}
+	if _, err := ParsePrivateKeyPEM([]byte("not a key"), nil); err == nil {
+		t.Error("ParsePrivateKeyPEM() error = nil, want error for missing key")
+	}
+}
+
+func TestDecryptPKCS8IterationCount(t *testing.T) {
+	data, err := os.ReadFile("testdata/key.enc.pem")
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		t.Fatal("pem.Decode() found no block")
+	}
+	for _, count := range []int{maxPBKDF2Iterations + 1, math.MaxInt32} {
+		der := withIterationCount(t, block.Bytes, count)
+		start := time.Now()
+		if _, err := decryptPKCS8(der, []byte("secret")); err == nil || !strings.Contains(err.Error(), "iteration count") {
+			t.Errorf("decryptPKCS8() with iteration count %d error = %v, want iteration count error", count, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("decryptPKCS8() with iteration count %d took %v", count, elapsed)
+		}
+	}
+}
+
+// withIterationCount re-encodes the encrypted private key with the PBKDF2
+// iteration count.
+func withIterationCount(t *testing.T, der []byte, count int) []byte {
+	t.Helper()
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		t.Fatalf("asn1.Unmarshal() error = %v", err)
+	}
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		t.Fatalf("asn1.Unmarshal() error = %v", err)
+	}
+	var kdfParams pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err != nil {
+		t.Fatalf("asn1.Unmarshal() error = %v", err)
+	}
+	kdfParams.IterationCount = count
+	var err error
+	if params.KeyDerivationFunc.Parameters.FullBytes, err = asn1.Marshal(kdfParams); err != nil {
+		t.Fatalf("asn1.Marshal() error = %v", err)
+	}
+	if info.Algorithm.Parameters.FullBytes, err = asn1.Marshal(params); err != nil {
+		t.Fatalf("asn1.Marshal() error = %v", err)
+	}
+	if der, err = asn1.Marshal(info); err != nil {
+		t.Fatalf("asn1.Marshal() error = %v", err)
+	}
+	return der
+}
+
+func TestParsePKCS12(t *testing.T) {
+	data, err := os.ReadFile("testdata/key.p12")
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	key, certs, err := ParsePKCS12(data, passphrase("secret"))
+	if err != nil {
+		t.Fatalf("ParsePKCS12() error = %v", err)
+	}
+	if len(certs) != 1 {
+		t.Fatalf("ParsePKCS12() certs = %d, want 1", len(certs))
+	}
+	if err := CheckKeyPair(key, certs[0]); err != nil {
+		t.Errorf("CheckKeyPair() error = %v", err)
+	}
+
+	if _, _, err := ParsePKCS12(data, passphrase("wrong")); !errors.Is(err, ErrIncorrectPassphrase) {
+		t.Errorf("ParsePKCS12() error = %v, want %v", err, ErrIncorrectPassphrase)
+	}
+}
@@ -0,0 +1,166 @@
+package keyutil
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Object identifiers of the PBES2 scheme.
+// Reference: RFC 8018 Appendix C.
+var (
+	oidPBES2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA224 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 8}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+
+	oidAES128CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// maxPBKDF2Iterations is the maximum iteration count of PBKDF2, which bounds
+// the time of deriving the key from a malicious encrypted private key.
+// It is far above the counts used by OpenSSL and recommended by OWASP.
+const maxPBKDF2Iterations = 10_000_000
+
+// encryptedPrivateKeyInfo is the PKCS#8 EncryptedPrivateKeyInfo structure.
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// pbes2Params is the PBES2-params structure.
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+// pbkdf2Params is the PBKDF2-params structure.
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// decryptPKCS8 decrypts the DER encoded PKCS#8 EncryptedPrivateKeyInfo
+// encrypted by PBES2 with PBKDF2 and AES-CBC, and returns the DER encoded
+// PKCS#8 PrivateKeyInfo.
+func decryptPKCS8(der, password []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if rest, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("invalid encrypted private key: %w", err)
+	} else if len(rest) > 0 {
+		return nil, errors.New("invalid encrypted private key: trailing data")
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported encryption scheme: %v", info.Algorithm.Algorithm)
+	}
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("invalid PBES2 parameters: %w", err)
+	}
+
+	// resolve cipher
+	var keyLen int
+	switch alg := params.EncryptionScheme.Algorithm; {
+	case alg.Equal(oidAES128CBC):
+		keyLen = 16
+	case alg.Equal(oidAES192CBC):
+		keyLen = 24
+	case alg.Equal(oidAES256CBC):
+		keyLen = 32
+	default:
+		return nil, fmt.Errorf("unsupported encryption algorithm: %v", alg)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, fmt.Errorf("invalid encryption parameters: %w", err)
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, errors.New("invalid encryption parameters: bad IV length")
+	}
+
+	// derive key
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("unsupported key derivation function: %v", params.KeyDerivationFunc.Algorithm)
+	}
+	var kdfParams pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err != nil {
+		return nil, fmt.Errorf("invalid PBKDF2 parameters: %w", err)
+	}
+	if kdfParams.KeyLength != 0 && kdfParams.KeyLength != keyLen {
+		return nil, errors.New("invalid PBKDF2 parameters: key length mismatch")
+	}
+	if kdfParams.IterationCount <= 0 {
+		return nil, errors.New("invalid PBKDF2 parameters: bad iteration count")
+	}
+	if kdfParams.IterationCount > maxPBKDF2Iterations {
+		return nil, fmt.Errorf("invalid PBKDF2 parameters: iteration count %d exceeds %d", kdfParams.IterationCount, maxPBKDF2Iterations)
+	}
+	prf, err := pbkdf2PRF(kdfParams.PRF.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	key := pbkdf2.Key(password, kdfParams.Salt, kdfParams.IterationCount, keyLen, prf)
+
+	// decrypt
+	data := info.EncryptedData
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid encrypted private key: bad data length")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, data)
+	return unpad(plaintext)
+}
+
+// pbkdf2PRF returns the hash function of the PBKDF2 pseudorandom function,
+// which defaults to HMAC-SHA1.
+func pbkdf2PRF(alg asn1.ObjectIdentifier) (func() hash.Hash, error) {
+	switch {
+	case len(alg) == 0, alg.Equal(oidHMACWithSHA1):
+		return sha1.New, nil
+	case alg.Equal(oidHMACWithSHA224):
+		return sha256.New224, nil
+	case alg.Equal(oidHMACWithSHA256):
+		return sha256.New, nil
+	case alg.Equal(oidHMACWithSHA384):
+		return sha512.New384, nil
+	case alg.Equal(oidHMACWithSHA512):
+		return sha512.New, nil
+	}
+	return nil, fmt.Errorf("unsupported PBKDF2 pseudorandom function: %v", alg)
+}
+
+// unpad removes the PKCS#7 padding. An invalid padding indicates an incorrect
+// passphrase.
+func unpad(data []byte) ([]byte, error) {
+	n := int(data[len(data)-1])
+	if n == 0 || n > aes.BlockSize {
+		return nil, ErrIncorrectPassphrase
+	}
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, ErrIncorrectPassphrase
+		}
+	}
+	return data[:len(data)-n], nil
+}
@@ -3,12 +3,26 @@ package cose
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"errors"
+	"fmt"
 
 	"github.com/veraison/go-cose"
 )
 
+// defaultAllowedAlgorithms lists the signing algorithms accepted by the
+// verifier if no allowlist is configured.
+var defaultAllowedAlgorithms = []cose.Algorithm{
+	cose.AlgorithmPS256,
+	cose.AlgorithmPS384,
+	cose.AlgorithmPS512,
+	cose.AlgorithmES256,
+	cose.AlgorithmES384,
+	cose.AlgorithmES512,
+	cose.AlgorithmEd25519,
+}
+
 // AlgorithmFromKey picks up a recommended algorithm for private and public
 // keys.
 // Reference: RFC 8152 8 Signature Algorithms.
@@ -42,6 +56,85 @@ func AlgorithmFromKey(key interface{}) (cose.Algorithm, error) {
 		default:
 			return 0, errors.New("ecdsa key not supported")
 		}
+	case ed25519.PublicKey:
+		return cose.AlgorithmEd25519, nil
 	}
 	return 0, errors.New("key not supported")
 }
+
+// ParseAlgorithm parses the name of a supported signing algorithm, such as
+// `PS256`, `ES384`, or `EdDSA`.
+func ParseAlgorithm(name string) (cose.Algorithm, error) {
+	for _, alg := range defaultAllowedAlgorithms {
+		if alg.String() == name {
+			return alg, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported algorithm: %s", name)
+}
+
+// hashFromAlgorithm returns the hash algorithm associated with the signing
+// algorithm. EdDSA has no associated hash algorithm.
+func hashFromAlgorithm(alg cose.Algorithm) (crypto.Hash, bool) {
+	switch alg {
+	case cose.AlgorithmPS256, cose.AlgorithmES256:
+		return crypto.SHA256, true
+	case cose.AlgorithmPS384, cose.AlgorithmES384:
+		return crypto.SHA384, true
+	case cose.AlgorithmPS512, cose.AlgorithmES512:
+		return crypto.SHA512, true
+	}
+	return 0, false
+}
+
+// digestToBeSigned returns the digest of the ToBeSigned bytes by the hash
+// algorithm of the signing algorithm. For EdDSA, the ToBeSigned bytes are
+// returned as is.
+func digestToBeSigned(alg cose.Algorithm, toBeSigned []byte) []byte {
+	hash, ok := hashFromAlgorithm(alg)
+	if !ok {
+		return toBeSigned
+	}
+	h := hash.New()
+	h.Write(toBeSigned)
+	return h.Sum(nil)
+}
+
+// validateAlgorithm checks if the signing algorithm is compatible with the
+// type and the size of the public key.
+// Reference: RFC 8230 6.1 Key Size Security Considerations.
+func validateAlgorithm(alg cose.Algorithm, key crypto.PublicKey) error {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		switch alg {
+		case cose.AlgorithmPS256, cose.AlgorithmPS384, cose.AlgorithmPS512:
+			if size := key.N.BitLen(); size < 2048 {
+				return fmt.Errorf("%w: %v requires RSA key of at least 2048 bits: got %d bits", ErrAlgorithmMismatch, alg, size)
+			}
+			return nil
+		}
+		return fmt.Errorf("%w: %v is not compatible with RSA key", ErrAlgorithmMismatch, alg)
+	case *ecdsa.PublicKey:
+		var bitSize int
+		switch alg {
+		case cose.AlgorithmES256:
+			bitSize = 256
+		case cose.AlgorithmES384:
+			bitSize = 384
+		case cose.AlgorithmES512:
+			bitSize = 521
+		default:
+			return fmt.Errorf("%w: %v is not compatible with ECDSA key", ErrAlgorithmMismatch, alg)
+		}
+		if curve := key.Curve.Params(); curve.BitSize != bitSize {
+			return fmt.Errorf("%w: %v is not compatible with ECDSA key on curve %s", ErrAlgorithmMismatch, alg, curve.Name)
+		}
+		return nil
+	case ed25519.PublicKey:
+		if alg != cose.AlgorithmEd25519 {
+			return fmt.Errorf("%w: %v is not compatible with Ed25519 key", ErrAlgorithmMismatch, alg)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %v is not compatible with key type %T", ErrAlgorithmMismatch, alg, key)
+}
@@ -0,0 +1,33 @@
+package cose
+
+import "time"
+
+// Clock provides the current time to the signers and the verifiers, so that
+// tests and replay tools can pin the time.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to the Clock interface.
+type ClockFunc func() time.Time
+
+// Now returns the time returned by the function.
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// FixedClock returns a clock always returning the specified time.
+func FixedClock(t time.Time) Clock {
+	return ClockFunc(func() time.Time {
+		return t
+	})
+}
+
+// now returns the time of the clock, or the system time if the clock is nil.
+func now(clock Clock) time.Time {
+	if clock == nil {
+		return time.Now()
+	}
+	return clock.Now()
+}
@@ -0,0 +1,57 @@
+package cose
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/notaryproject/notation-go"
+)
+
+func TestClockSkew(t *testing.T) {
+	// sign by a signer with its clock ahead
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	signer, cert := newTestSigner(t)
+	signingTime := time.Now().Add(30 * time.Second).Truncate(time.Second)
+	signer.Clock = FixedClock(signingTime)
+	sig, err := signer.Sign(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	v := NewVerifier()
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	v.VerifyOptions.Roots = roots
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); !errors.Is(err, ErrSignatureNotYetValid) {
+		t.Fatalf("Verify() error = %v, want %v", err, ErrSignatureNotYetValid)
+	}
+
+	// tolerate the clock skew
+	v.AllowedClockSkew = time.Minute
+	result, err := v.VerifyWithResult(ctx, sig, notation.VerifyOptions{})
+	if err != nil {
+		t.Fatalf("VerifyWithResult() error = %v", err)
+	}
+	if !result.SigningTime.Equal(signingTime) {
+		t.Errorf("VerifyWithResult() SigningTime = %v, want %v", result.SigningTime, signingTime)
+	}
+
+	// pin the clock of the verifier
+	v.AllowedClockSkew = 0
+	v.Clock = FixedClock(signingTime.Add(time.Second))
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	v.Clock = FixedClock(sOpts.Expiry.Add(time.Second))
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); !errors.Is(err, ErrSignatureExpired) {
+		t.Errorf("Verify() error = %v, want %v", err, ErrSignatureExpired)
+	}
+	v.AllowedClockSkew = time.Minute
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
@@ -0,0 +1,325 @@
+package cose
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/notaryproject/notation-go"
+	"github.com/veraison/go-cose"
+)
+
+// headerLabelCountersignature is the label of the unprotected header parameter
+// holding the full countersignatures of version 2.
+// Reference: RFC 9338 3.1 Full Countersignatures.
+const headerLabelCountersignature int64 = 11
+
+// CountersignaturePolicy validates the countersignatures of the COSE_Sign1
+// signatures, such as the approvals added to the signatures after signing.
+type CountersignaturePolicy struct {
+	// Required requires at least one countersignature.
+	Required bool
+
+	// VerifyOptions is the verify option to verify the certificates of the
+	// countersigners.
+	// The `Intermediates` in the verify options will be ignored and
+	// re-contrusted using the certificates in the countersignature.
+	// An empty list of `KeyUsages` in the verify options implies
+	// `ExtKeyUsageAny`.
+	VerifyOptions x509.VerifyOptions
+
+	// IdentityConstraints pins the acceptable identities of the countersigners
+	// if present.
+	IdentityConstraints *IdentityConstraints
+}
+
+// Countersign adds the countersignature of the signer to the COSE_Sign1
+// signature without invalidating it. The countersignature signs the protected
+// header, the payload, and the signature of the countersigned signature, and
+// is stored in its unprotected header.
+// Reference: RFC 9338 COSE Countersignatures.
+func (s *Signer) Countersign(ctx context.Context, signature []byte, opts notation.SignOptions) ([]byte, error) {
+	return s.countersign(ctx, signature, nil, opts)
+}
+
+// CountersignBlob adds the countersignature of the signer to the COSE_Sign1
+// signature with the payload detached, which is generated by `SignBlob` for
+// the content.
+func (s *Signer) CountersignBlob(ctx context.Context, signature, content []byte, opts notation.SignOptions) ([]byte, error) {
+	if content == nil {
+		content = []byte{}
+	}
+	return s.countersign(ctx, signature, content, opts)
+}
+
+// countersign adds the countersignature of the signer to the signature. The
+// payload embedded in the signature is countersigned if the detached payload
+// is nil.
+func (s *Signer) countersign(ctx context.Context, signature, detachedPayload []byte, opts notation.SignOptions) ([]byte, error) {
+	if s.base == nil && s.external == nil {
+		return nil, errors.New("signer has no signing key")
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	// unpack envelope
+	msg := &cose.Sign1Message{}
+	if err := msg.UnmarshalCBOR(signature); err != nil {
+		return nil, &EnvelopeError{Err: err}
+	}
+	payload := msg.Payload
+	if detachedPayload != nil {
+		if payload != nil {
+			return nil, &EnvelopeError{Err: errors.New("payload not detached")}
+		}
+		payload = detachedPayload
+	} else if payload == nil {
+		return nil, &EnvelopeError{Err: errors.New("missing payload")}
+	}
+	if err := verifyCountersigned(msg, payload); err != nil {
+		return nil, err
+	}
+
+	// generate countersignature
+	countersig := cose.NewSignature()
+	countersig.Headers.Protected = cose.ProtectedHeader{
+		cose.HeaderLabelAlgorithm: s.alg,
+		cose.HeaderLabelX5Chain:   s.certChain,
+		"signingtime":             now(s.Clock),
+	}
+	if !opts.Expiry.IsZero() {
+		countersig.Headers.Protected["exp"] = opts.Expiry.Unix()
+	}
+	protected, err := countersig.Headers.MarshalProtected()
+	if err != nil {
+		return nil, err
+	}
+	countersig.Headers.RawProtected = protected
+	toBeSigned, err := countersignStructure(msg.Headers.RawProtected, protected, payload, msg.Signature)
+	if err != nil {
+		return nil, err
+	}
+	if countersig.Signature, err = s.signToBeSigned(ctx, toBeSigned); err != nil {
+		return nil, err
+	}
+	if err := s.stampSignature(ctx, countersig.Signature, countersig.Headers.Unprotected, opts); err != nil {
+		return nil, err
+	}
+	encoded, err := countersig.MarshalCBOR()
+	if err != nil {
+		return nil, err
+	}
+
+	// add countersignature to the unprotected header
+	value := cbor.RawMessage(encoded)
+	switch existing := msg.Headers.Unprotected[headerLabelCountersignature].(type) {
+	case nil:
+		msg.Headers.Unprotected[headerLabelCountersignature] = value
+	case []interface{}:
+		if isSingleCountersignature(existing) {
+			msg.Headers.Unprotected[headerLabelCountersignature] = []interface{}{existing, value}
+		} else {
+			msg.Headers.Unprotected[headerLabelCountersignature] = append(existing, value)
+		}
+	default:
+		return nil, &EnvelopeError{
+			Label: headerLabelCountersignature,
+			Value: existing,
+			Err:   errors.New("invalid countersignature"),
+		}
+	}
+	msg.Headers.RawUnprotected = nil
+
+	// encode in CBOR
+	return msg.MarshalCBOR()
+}
+
+// verifyCountersigned verifies the COSE_Sign1 message to be countersigned
+// against its signing certificate so that only intact signatures are
+// countersigned. The certificate chain is not verified.
+func verifyCountersigned(msg *cose.Sign1Message, payload []byte) error {
+	alg, err := msg.Headers.Protected.Algorithm()
+	if err != nil {
+		return &EnvelopeError{
+			Label: cose.HeaderLabelAlgorithm,
+			Value: msg.Headers.Protected[cose.HeaderLabelAlgorithm],
+			Err:   err,
+		}
+	}
+	certChain, err := certChainFromHeader(msg.Headers.Protected)
+	if err != nil {
+		return err
+	}
+	cert, err := x509.ParseCertificate(certChain[0])
+	if err != nil {
+		return &EnvelopeError{
+			Label: cose.HeaderLabelX5Chain,
+			Value: certChain[0],
+			Err:   err,
+		}
+	}
+	verifier, err := cose.NewVerifier(alg, cert.PublicKey)
+	if err != nil {
+		return err
+	}
+	countersigned := *msg
+	countersigned.Payload = payload
+	if err := countersigned.Verify(nil, verifier); err != nil {
+		return &SignatureError{Err: err}
+	}
+	return nil
+}
+
+// signToBeSigned signs the ToBeSigned bytes by the key of the signer. The
+// signature of the external signer is verified against the signing
+// certificate.
+func (s *Signer) signToBeSigned(ctx context.Context, toBeSigned []byte) ([]byte, error) {
+	digest := digestToBeSigned(s.alg, toBeSigned)
+	if s.external == nil {
+		return s.base.Sign(rand.Reader, digest)
+	}
+	sig, err := s.external.Sign(ctx, s.alg, digest)
+	if err != nil {
+		return nil, fmt.Errorf("external signer failed: %w", err)
+	}
+	verifier, err := cose.NewVerifier(s.alg, s.certs[0].PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifier.Verify(digest, sig); err != nil {
+		return nil, fmt.Errorf("external signer returned a signature not matching the signing certificate: %w", ErrInvalidSignature)
+	}
+	return sig, nil
+}
+
+// verifyCountersignatures verifies the countersignatures of the verified
+// COSE_Sign1 message against the countersignature policy, and records them in
+// the verification result. The countersigners are verified as the signer is
+// except for the certificate verify options and the identity constraints of
+// the policy.
+func (v *Verifier) verifyCountersignatures(ctx context.Context, msg *cose.Sign1Message, result *VerificationResult) error {
+	policy := v.Countersignature
+	countersigs, err := countersignaturesFromHeader(msg.Headers.Unprotected)
+	if err != nil {
+		return err
+	}
+	if len(countersigs) == 0 {
+		if policy.Required {
+			return ErrMissingCountersignature
+		}
+		return nil
+	}
+
+	countersigner := *v
+	countersigner.VerifyOptions = policy.VerifyOptions
+	countersigner.IdentityConstraints = policy.IdentityConstraints
+	countersigner.TrustPolicy = nil
+	countersigner.Countersignature = nil
+	if !v.currentTime.IsZero() {
+		countersigner.VerifyOptions.CurrentTime = v.currentTime
+	}
+	for i, countersig := range countersigs {
+		countersigResult, err := countersigner.verifyCountersignature(ctx, msg, countersig)
+		if err != nil {
+			return &CountersignatureError{Index: i, Err: err}
+		}
+		result.Countersignatures = append(result.Countersignatures, countersigResult)
+	}
+	return nil
+}
+
+// verifyCountersignature verifies a countersignature of the COSE_Sign1
+// message, and returns its verification result.
+func (v *Verifier) verifyCountersignature(ctx context.Context, msg *cose.Sign1Message, countersig *cose.Signature) (*VerificationResult, error) {
+	result := &VerificationResult{
+		UnprotectedHeaders: countersig.Headers.Unprotected,
+	}
+	verifier, err := v.verifySigner(ctx, &countersig.Headers, countersig.Signature, result)
+	if err != nil {
+		return nil, err
+	}
+	alg, err := countersig.Headers.Protected.Algorithm()
+	if err != nil {
+		return nil, err
+	}
+
+	// verify countersignature
+	toBeSigned, err := countersignStructure(msg.Headers.RawProtected, countersig.Headers.RawProtected, msg.Payload, msg.Signature)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifier.Verify(digestToBeSigned(alg, toBeSigned), countersig.Signature); err != nil {
+		return nil, &SignatureError{Err: err}
+	}
+	if err := v.verifyAttributes(countersig.Headers.Protected, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// countersignStructure encodes the Countersign_structure of a full
+// countersignature of version 2 over a COSE_Sign1 message without external
+// data.
+// Reference: RFC 9338 3.3 The Countersign_structure.
+func countersignStructure(bodyProtected, signProtected, payload, signature []byte) ([]byte, error) {
+	return cbor.Marshal([]interface{}{
+		"CounterSignatureV2",           // context
+		cbor.RawMessage(bodyProtected), // body_protected
+		cbor.RawMessage(signProtected), // sign_protected
+		[]byte{},                       // external_aad
+		payload,                        // payload
+		[]interface{}{signature},       // other_fields
+	})
+}
+
+// countersignaturesFromHeader reads the countersignatures from the
+// unprotected header, which holds either a single countersignature or an array
+// of countersignatures.
+func countersignaturesFromHeader(header cose.UnprotectedHeader) ([]*cose.Signature, error) {
+	value, ok := header[headerLabelCountersignature]
+	if !ok {
+		return nil, nil
+	}
+	invalid := func(err error) error {
+		return &EnvelopeError{
+			Label: headerLabelCountersignature,
+			Value: value,
+			Err:   fmt.Errorf("invalid countersignature: %w", err),
+		}
+	}
+	items, ok := value.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, invalid(errors.New("not an array"))
+	}
+	if isSingleCountersignature(items) {
+		items = []interface{}{items}
+	}
+	countersigs := make([]*cose.Signature, 0, len(items))
+	for _, item := range items {
+		encoded, err := cbor.Marshal(item)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		countersig := &cose.Signature{}
+		if err := countersig.UnmarshalCBOR(encoded); err != nil {
+			return nil, invalid(err)
+		}
+		countersigs = append(countersigs, countersig)
+	}
+	return countersigs, nil
+}
+
+// isSingleCountersignature reports whether the decoded value of the
+// countersignature header parameter is a single COSE_Countersignature rather
+// than an array of them, by its protected header in a byte string.
+func isSingleCountersignature(items []interface{}) bool {
+	if len(items) == 0 {
+		return false
+	}
+	_, ok := items[0].([]byte)
+	return ok
+}
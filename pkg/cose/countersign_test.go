@@ -0,0 +1,170 @@
+package cose
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"testing"
+
+	"github.com/notaryproject/notation-go"
+)
+
+func TestCountersign(t *testing.T) {
+	// sign and countersign
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	signer, cert := newTestSigner(t)
+	approver, approverCert := newTestSigner(t)
+	sig, err := signer.Sign(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	countersigned, err := approver.Countersign(ctx, sig, sOpts)
+	if err != nil {
+		t.Fatalf("Countersign() error = %v", err)
+	}
+
+	// the countersigned signature remains valid without the policy
+	v := NewVerifier()
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	v.VerifyOptions.Roots = roots
+	if _, err := v.Verify(ctx, countersigned, notation.VerifyOptions{}); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	// verify countersignature
+	approverRoots := x509.NewCertPool()
+	approverRoots.AddCert(approverCert)
+	v.Countersignature = &CountersignaturePolicy{
+		Required: true,
+		VerifyOptions: x509.VerifyOptions{
+			Roots: approverRoots,
+		},
+	}
+	result, err := v.VerifyWithResult(ctx, countersigned, notation.VerifyOptions{})
+	if err != nil {
+		t.Fatalf("VerifyWithResult() error = %v", err)
+	}
+	if len(result.Countersignatures) != 1 {
+		t.Fatalf("VerifyWithResult() got %d countersignatures, want 1", len(result.Countersignatures))
+	}
+	if got := result.Countersignatures[0].Signer(); !got.Equal(approverCert) {
+		t.Errorf("VerifyWithResult() countersigner = %v, want %v", got.Subject, approverCert.Subject)
+	}
+
+	// required countersignature
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); !errors.Is(err, ErrMissingCountersignature) {
+		t.Errorf("Verify() error = %v, want %v", err, ErrMissingCountersignature)
+	}
+
+	// untrusted countersigner
+	other, _ := newTestSigner(t)
+	countersigned, err = other.Countersign(ctx, countersigned, sOpts)
+	if err != nil {
+		t.Fatalf("Countersign() error = %v", err)
+	}
+	_, err = v.Verify(ctx, countersigned, notation.VerifyOptions{})
+	var countersigErr *CountersignatureError
+	if !errors.As(err, &countersigErr) || countersigErr.Index != 1 || !errors.Is(err, ErrUntrustedSigner) {
+		t.Errorf("Verify() error = %v, want untrusted second countersigner", err)
+	}
+}
+
+func TestCountersignBlob(t *testing.T) {
+	ctx := context.Background()
+	_, sOpts := generateSigningContent(nil)
+	signer, cert := newTestSigner(t)
+	approver, approverCert := newTestSigner(t)
+	content := []byte("hello world")
+	sig, err := signer.SignBlob(ctx, content, sOpts)
+	if err != nil {
+		t.Fatalf("SignBlob() error = %v", err)
+	}
+	if _, err := approver.Countersign(ctx, sig, sOpts); !errors.Is(err, ErrInvalidEnvelope) {
+		t.Errorf("Countersign() error = %v, want %v", err, ErrInvalidEnvelope)
+	}
+	countersigned, err := approver.CountersignBlob(ctx, sig, content, sOpts)
+	if err != nil {
+		t.Fatalf("CountersignBlob() error = %v", err)
+	}
+
+	v := NewVerifier()
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	v.VerifyOptions.Roots = roots
+	approverRoots := x509.NewCertPool()
+	approverRoots.AddCert(approverCert)
+	v.Countersignature = &CountersignaturePolicy{
+		Required: true,
+		VerifyOptions: x509.VerifyOptions{
+			Roots: approverRoots,
+		},
+	}
+	if err := v.VerifyBlob(ctx, countersigned, content, notation.VerifyOptions{}); err != nil {
+		t.Fatalf("VerifyBlob() error = %v", err)
+	}
+
+	// the countersignature covers the content
+	if err := v.VerifyBlob(ctx, countersigned, []byte("tampered"), notation.VerifyOptions{}); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifyBlob() error = %v, want %v", err, ErrInvalidSignature)
+	}
+	if _, err := approver.CountersignBlob(ctx, sig, []byte("tampered"), sOpts); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("CountersignBlob() error = %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestCountersignRevoked(t *testing.T) {
+	ca, err := newTestCA()
+	if err != nil {
+		t.Fatalf("newTestCA() error = %v", err)
+	}
+	server := ca.startServer()
+	defer server.Close()
+	key, approverCert, err := ca.issue(server.URL+"/ocsp", "")
+	if err != nil {
+		t.Fatalf("testCA.issue() error = %v", err)
+	}
+	approver, err := NewSigner(key, []*x509.Certificate{approverCert, ca.cert})
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+
+	// sign and countersign
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	signer, cert := newTestSigner(t)
+	sig, err := signer.Sign(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	countersigned, err := approver.Countersign(ctx, sig, sOpts)
+	if err != nil {
+		t.Fatalf("Countersign() error = %v", err)
+	}
+
+	// the countersigner is checked for revocation as the signer is
+	v := NewVerifier()
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	v.VerifyOptions.Roots = roots
+	approverRoots := x509.NewCertPool()
+	approverRoots.AddCert(ca.cert)
+	v.Countersignature = &CountersignaturePolicy{
+		Required: true,
+		VerifyOptions: x509.VerifyOptions{
+			Roots: approverRoots,
+		},
+	}
+	v.Revocation = NewRevocationChecker(&HTTPFetcher{Client: server.Client()}, RevocationModeHardFail)
+	if _, err := v.Verify(ctx, countersigned, notation.VerifyOptions{}); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	ca.revoke(approverCert)
+	v.Revocation = NewRevocationChecker(&HTTPFetcher{Client: server.Client()}, RevocationModeHardFail)
+	_, err = v.Verify(ctx, countersigned, notation.VerifyOptions{})
+	var countersigErr *CountersignatureError
+	if !errors.As(err, &countersigErr) || !errors.Is(err, ErrCertificateRevoked) {
+		t.Errorf("Verify() error = %v, want revoked countersigner", err)
+	}
+}
@@ -0,0 +1,101 @@
+package cose
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/veraison/go-cose"
+)
+
+// CriticalHeaderHandler processes the value of a critical header parameter
+// understood by the caller, and returns an error to reject the signature.
+type CriticalHeaderHandler func(value interface{}) error
+
+// knownCriticalHeaders lists the labels of the protected header parameters
+// processed by the verifier, which are understood if marked critical.
+var knownCriticalHeaders = []interface{}{
+	cose.HeaderLabelAlgorithm,
+	cose.HeaderLabelContentType,
+	cose.HeaderLabelX5Chain,
+	"signingtime",
+	"exp",
+}
+
+// verifyCritical verifies that the header parameters listed in the crit
+// parameter are present in the protected header and understood by the
+// verifier, and processes them by the registered handlers.
+// Reference: RFC 9052 3.1 Common COSE Header Parameters.
+func (v *Verifier) verifyCritical(header cose.ProtectedHeader) error {
+	value, ok := header[cose.HeaderLabelCritical]
+	if !ok {
+		return nil
+	}
+	labels, ok := value.([]interface{})
+	if !ok || len(labels) == 0 {
+		return &EnvelopeError{
+			Label: cose.HeaderLabelCritical,
+			Value: value,
+			Err:   errors.New("crit must be a non-empty array"),
+		}
+	}
+	for _, label := range labels {
+		switch label.(type) {
+		case int64, string:
+		default:
+			return &EnvelopeError{
+				Label: cose.HeaderLabelCritical,
+				Value: label,
+				Err:   errors.New("invalid critical header label"),
+			}
+		}
+		headerValue, ok := header[label]
+		if !ok {
+			return &EnvelopeError{
+				Label: cose.HeaderLabelCritical,
+				Value: label,
+				Err:   fmt.Errorf("critical header %v not present in the protected header", label),
+			}
+		}
+		if handler := v.criticalHeaderHandler(label); handler != nil {
+			if err := handler(headerValue); err != nil {
+				return &EnvelopeError{
+					Label: label,
+					Value: headerValue,
+					Err:   err,
+				}
+			}
+			continue
+		}
+		if !isKnownCriticalHeader(label) {
+			return &EnvelopeError{
+				Label: label,
+				Value: headerValue,
+				Err:   ErrUnknownCriticalHeader,
+			}
+		}
+	}
+	return nil
+}
+
+// criticalHeaderHandler returns the registered handler of the critical header
+// parameter. Integer labels registered as int are accepted as well.
+func (v *Verifier) criticalHeaderHandler(label interface{}) CriticalHeaderHandler {
+	if handler, ok := v.CriticalHeaderHandlers[label]; ok {
+		return handler
+	}
+	if label, ok := label.(int64); ok {
+		return v.CriticalHeaderHandlers[int(label)]
+	}
+	return nil
+}
+
+// isKnownCriticalHeader reports whether the critical header parameter is
+// processed by the verifier.
+func isKnownCriticalHeader(label interface{}) bool {
+	for _, known := range knownCriticalHeaders {
+		if label == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package cose
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"testing"
+
+	"github.com/notaryproject/notation-go"
+	"github.com/veraison/go-cose"
+)
+
+func TestVerifyCriticalHeaders(t *testing.T) {
+	// sign with a critical header parameter
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	signer, cert := newTestSigner(t)
+	signer.ProtectedHeaders = cose.ProtectedHeader{
+		"build": int64(42),
+	}
+	signer.CriticalHeaders = []interface{}{"build"}
+	sig, err := signer.Sign(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	// unknown critical header parameter
+	v := NewVerifier()
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	v.VerifyOptions.Roots = roots
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); !errors.Is(err, ErrUnknownCriticalHeader) {
+		t.Fatalf("Verify() error = %v, want %v", err, ErrUnknownCriticalHeader)
+	}
+
+	// critical header parameter understood by the handler
+	var got interface{}
+	v.CriticalHeaderHandlers = map[interface{}]CriticalHeaderHandler{
+		"build": func(value interface{}) error {
+			got = value
+			return nil
+		},
+	}
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if got != int64(42) {
+		t.Errorf("CriticalHeaderHandler() value = %v, want 42", got)
+	}
+
+	// critical header parameter rejected by the handler
+	v.CriticalHeaderHandlers["build"] = func(value interface{}) error {
+		return errors.New("unsupported build")
+	}
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); !errors.Is(err, ErrInvalidEnvelope) {
+		t.Errorf("Verify() error = %v, want %v", err, ErrInvalidEnvelope)
+	}
+
+	// critical header parameter not in the protected headers
+	signer.CriticalHeaders = []interface{}{"note"}
+	if _, err := signer.Sign(ctx, desc, sOpts); err == nil {
+		t.Error("Sign() error = nil, want error for missing critical header")
+	}
+}
+
+func TestVerifyCritical(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  cose.ProtectedHeader
+		wantErr error
+	}{
+		{
+			name: "no crit",
+			header: cose.ProtectedHeader{
+				cose.HeaderLabelAlgorithm: cose.AlgorithmPS256,
+			},
+		},
+		{
+			name: "known headers",
+			header: cose.ProtectedHeader{
+				cose.HeaderLabelCritical:    []interface{}{int64(cose.HeaderLabelContentType), "exp"},
+				cose.HeaderLabelContentType: "application/vnd.cncf.notary.v2.signature",
+				"exp":                       int64(0),
+			},
+		},
+		{
+			name: "empty crit",
+			header: cose.ProtectedHeader{
+				cose.HeaderLabelCritical: []interface{}{},
+			},
+			wantErr: ErrInvalidEnvelope,
+		},
+		{
+			name: "crit not an array",
+			header: cose.ProtectedHeader{
+				cose.HeaderLabelCritical: "exp",
+			},
+			wantErr: ErrInvalidEnvelope,
+		},
+		{
+			name: "critical header not present",
+			header: cose.ProtectedHeader{
+				cose.HeaderLabelCritical: []interface{}{"exp"},
+			},
+			wantErr: ErrInvalidEnvelope,
+		},
+		{
+			name: "unknown integer label",
+			header: cose.ProtectedHeader{
+				cose.HeaderLabelCritical: []interface{}{int64(-70000)},
+				int64(-70000):            "value",
+			},
+			wantErr: ErrUnknownCriticalHeader,
+		},
+	}
+	v := NewVerifier()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.verifyCritical(tt.header)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("verifyCritical() error = %v", err)
+				}
+			} else if !errors.Is(err, tt.wantErr) {
+				t.Errorf("verifyCritical() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package cose
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Common errors returned by the verifier, which can be inspected by
+// `errors.Is`.
+var (
+	// ErrInvalidEnvelope indicates that the signature envelope is malformed
+	// or misses required parameters.
+	ErrInvalidEnvelope = errors.New("invalid signature envelope")
+
+	// ErrInvalidSignature indicates that the signature does not match the
+	// signed content.
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrUntrustedSigner indicates that the signing certificate chain cannot
+	// be verified against the trusted roots.
+	ErrUntrustedSigner = errors.New("untrusted signer")
+
+	// ErrSignatureExpired indicates that the signature is expired.
+	ErrSignatureExpired = errors.New("signature expired")
+
+	// ErrSignatureNotYetValid indicates that the signing time of the signature
+	// is in the future.
+	ErrSignatureNotYetValid = errors.New("signature not yet valid")
+
+	// ErrTimestampInvalid indicates that the timestamp countersignature is
+	// missing or cannot be verified.
+	ErrTimestampInvalid = errors.New("invalid timestamp")
+
+	// ErrAlgorithmMismatch indicates that the signing algorithm in the
+	// protected header is not compatible with the public key of the signing
+	// certificate.
+	ErrAlgorithmMismatch = errors.New("algorithm mismatch")
+
+	// ErrAlgorithmNotAllowed indicates that the signing algorithm is not in the
+	// allowlist of the verifier.
+	ErrAlgorithmNotAllowed = errors.New("algorithm not allowed")
+
+	// ErrCertificateRevoked indicates that a certificate in the signing
+	// certificate chain is revoked.
+	ErrCertificateRevoked = errors.New("certificate revoked")
+
+	// ErrRevocationUnknown indicates that the revocation status of a
+	// certificate cannot be determined.
+	ErrRevocationUnknown = errors.New("revocation status unknown")
+
+	// ErrDescriptorMismatch indicates that the verified descriptor does not
+	// match the descriptor of the artifact expected by the caller.
+	ErrDescriptorMismatch = errors.New("descriptor mismatch")
+
+	// ErrUnknownCriticalHeader indicates that a header parameter marked
+	// critical is not understood by the verifier.
+	ErrUnknownCriticalHeader = errors.New("unknown critical header")
+
+	// ErrMissingCountersignature indicates that the countersignature required
+	// by the verifier is not present.
+	ErrMissingCountersignature = errors.New("missing countersignature")
+)
+
+// EnvelopeError is returned when the signature envelope cannot be decoded or
+// a header parameter is missing or invalid.
+type EnvelopeError struct {
+	// Label is the label of the offending header parameter if present.
+	Label interface{}
+
+	// Value is the offending value of the header parameter if present.
+	Value interface{}
+
+	// Err is the cause of the error.
+	Err error
+}
+
+// Error returns the error message.
+func (e *EnvelopeError) Error() string {
+	if e.Label == nil {
+		return fmt.Sprintf("%v: %v", ErrInvalidEnvelope, e.Err)
+	}
+	return fmt.Sprintf("%v: header %v: %v", ErrInvalidEnvelope, e.Label, e.Err)
+}
+
+// Is reports whether the target is ErrInvalidEnvelope.
+func (e *EnvelopeError) Is(target error) bool {
+	return target == ErrInvalidEnvelope
+}
+
+// Unwrap returns the cause of the error.
+func (e *EnvelopeError) Unwrap() error {
+	return e.Err
+}
+
+// SignatureError is returned when the signature does not match the signed
+// content.
+type SignatureError struct {
+	// Err is the cause of the error.
+	Err error
+}
+
+// Error returns the error message.
+func (e *SignatureError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrInvalidSignature, e.Err)
+}
+
+// Is reports whether the target is ErrInvalidSignature.
+func (e *SignatureError) Is(target error) bool {
+	return target == ErrInvalidSignature
+}
+
+// Unwrap returns the cause of the error.
+func (e *SignatureError) Unwrap() error {
+	return e.Err
+}
+
+// CertificateError is returned when the signing certificate chain cannot be
+// verified.
+type CertificateError struct {
+	// Certificate is the signing certificate.
+	Certificate *x509.Certificate
+
+	// Err is the cause of the error.
+	Err error
+}
+
+// Error returns the error message.
+func (e *CertificateError) Error() string {
+	return fmt.Sprintf("%v %q: %v", ErrUntrustedSigner, e.Certificate.Subject, e.Err)
+}
+
+// Is reports whether the target is ErrUntrustedSigner.
+func (e *CertificateError) Is(target error) bool {
+	return target == ErrUntrustedSigner
+}
+
+// Unwrap returns the cause of the error.
+func (e *CertificateError) Unwrap() error {
+	return e.Err
+}
+
+// IdentityError is returned when the signing certificate violates the
+// identity constraints.
+type IdentityError struct {
+	// Certificate is the signing certificate.
+	Certificate *x509.Certificate
+
+	// Violations describes each failed constraint.
+	Violations []string
+}
+
+// Error returns the error message.
+func (e *IdentityError) Error() string {
+	return fmt.Sprintf("%v %q: identity constraints not satisfied: %s", ErrUntrustedSigner, e.Certificate.Subject, strings.Join(e.Violations, "; "))
+}
+
+// Is reports whether the target is ErrUntrustedSigner.
+func (e *IdentityError) Is(target error) bool {
+	return target == ErrUntrustedSigner
+}
+
+// SignatureExpiredError is returned when the signature is expired.
+type SignatureExpiredError struct {
+	// Expiry is the expiration time of the signature.
+	Expiry time.Time
+
+	// CurrentTime is the time when the signature is verified.
+	CurrentTime time.Time
+}
+
+// Error returns the error message.
+func (e *SignatureExpiredError) Error() string {
+	return fmt.Sprintf("signature is expired by %v", e.CurrentTime.Sub(e.Expiry))
+}
+
+// Is reports whether the target is ErrSignatureExpired.
+func (e *SignatureExpiredError) Is(target error) bool {
+	return target == ErrSignatureExpired
+}
+
+// SigningTimeError is returned when the signing time of the signature is
+// after the time of verification.
+type SigningTimeError struct {
+	// SigningTime is the signing time of the signature.
+	SigningTime time.Time
+
+	// CurrentTime is the time when the signature is verified.
+	CurrentTime time.Time
+}
+
+// Error returns the error message.
+func (e *SigningTimeError) Error() string {
+	return fmt.Sprintf("signature used before generated: signed %v ahead", e.SigningTime.Sub(e.CurrentTime))
+}
+
+// Is reports whether the target is ErrSignatureNotYetValid.
+func (e *SigningTimeError) Is(target error) bool {
+	return target == ErrSignatureNotYetValid
+}
+
+// TimestampError is returned when the timestamp countersignature is missing
+// or cannot be verified.
+type TimestampError struct {
+	// Err is the cause of the error.
+	Err error
+}
+
+// Error returns the error message.
+func (e *TimestampError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrTimestampInvalid, e.Err)
+}
+
+// Is reports whether the target is ErrTimestampInvalid.
+func (e *TimestampError) Is(target error) bool {
+	return target == ErrTimestampInvalid
+}
+
+// Unwrap returns the cause of the error.
+func (e *TimestampError) Unwrap() error {
+	return e.Err
+}
+
+// RevokedError is returned when a certificate is revoked.
+type RevokedError struct {
+	// Certificate is the revoked certificate.
+	Certificate *x509.Certificate
+
+	// RevocationTime is the time when the certificate is revoked.
+	RevocationTime time.Time
+
+	// Reason is the revocation reason code if reported by OCSP.
+	// Reference: RFC 5280 5.3.1 Reason Code.
+	Reason int
+}
+
+// Error returns the error message.
+func (e *RevokedError) Error() string {
+	return fmt.Sprintf("certificate %q revoked at %v", e.Certificate.Subject, e.RevocationTime)
+}
+
+// Is reports whether the target is ErrCertificateRevoked.
+func (e *RevokedError) Is(target error) bool {
+	return target == ErrCertificateRevoked
+}
+
+// RevocationCheckError is returned when the revocation status of a
+// certificate cannot be determined in the hard-fail mode.
+type RevocationCheckError struct {
+	// Certificate is the certificate to be checked.
+	Certificate *x509.Certificate
+
+	// Errs contains the errors from each revocation endpoint.
+	Errs []error
+}
+
+// Error returns the error message.
+func (e *RevocationCheckError) Error() string {
+	return fmt.Sprintf("%v: certificate %q: %v", ErrRevocationUnknown, e.Certificate.Subject, e.Errs)
+}
+
+// Is reports whether the target is ErrRevocationUnknown.
+func (e *RevocationCheckError) Is(target error) bool {
+	return target == ErrRevocationUnknown
+}
+
+// ThresholdError is returned when a COSE_Sign envelope is not signed by
+// enough trusted parties to satisfy the threshold policy.
+type ThresholdError struct {
+	// Threshold is the required number of satisfied trust stores.
+	Threshold int
+
+	// Satisfied is the number of trust stores satisfied by the signatures.
+	Satisfied int
+
+	// Errs contains the failures of the signatures not verified against any
+	// trust store.
+	Errs []error
+}
+
+// Error returns the error message.
+func (e *ThresholdError) Error() string {
+	if len(e.Errs) == 0 {
+		return fmt.Sprintf("%v: %d of %d required trust stores satisfied", ErrUntrustedSigner, e.Satisfied, e.Threshold)
+	}
+	return fmt.Sprintf("%v: %d of %d required trust stores satisfied: %v", ErrUntrustedSigner, e.Satisfied, e.Threshold, e.Errs)
+}
+
+// Is reports whether the target is ErrUntrustedSigner.
+func (e *ThresholdError) Is(target error) bool {
+	return target == ErrUntrustedSigner
+}
+
+// CountersignatureError is returned when a countersignature cannot be
+// verified.
+type CountersignatureError struct {
+	// Index is the index of the countersignature.
+	Index int
+
+	// Err is the cause of the error.
+	Err error
+}
+
+// Error returns the error message.
+func (e *CountersignatureError) Error() string {
+	return fmt.Sprintf("countersignature %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the cause of the error.
+func (e *CountersignatureError) Unwrap() error {
+	return e.Err
+}
+
+// TSAUnavailableError is returned when a TSA cannot be reached or responds
+// with a server error. Only such failures are retried by the timestamp
+// policy.
+type TSAUnavailableError struct {
+	// Err is the cause of the error.
+	Err error
+}
+
+// Error returns the error message.
+func (e *TSAUnavailableError) Error() string {
+	return fmt.Sprintf("tsa unavailable: %v", e.Err)
+}
+
+// Unwrap returns the cause of the error.
+func (e *TSAUnavailableError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,162 @@
+package cose
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+
+	"github.com/veraison/go-cose"
+)
+
+// maxExternalSignResponseSize specifies the max size of the response of an
+// external signing backend.
+const maxExternalSignResponseSize = 1024 * 1024
+
+// ExternalSigner signs on behalf of the plugin so that the signing key never
+// enters the plugin process, such as a key in an HSM or a remote signing
+// service.
+type ExternalSigner interface {
+	// Sign signs the digest of the COSE ToBeSigned bytes with the algorithm,
+	// and returns the raw signature in the format of RFC 8152 section 8.
+	// For EdDSA, which has no associated hash algorithm, the ToBeSigned bytes
+	// are passed as the digest.
+	Sign(ctx context.Context, alg cose.Algorithm, digest []byte) ([]byte, error)
+}
+
+// ExternalSignRequest is the JSON request sent to the external signing
+// backends by HTTPSigner and CommandSigner.
+type ExternalSignRequest struct {
+	// KeyID identifies the key in the backend if present.
+	KeyID string `json:"keyId,omitempty"`
+
+	// Algorithm is the name of the COSE signing algorithm, such as `PS256`.
+	Algorithm string `json:"algorithm"`
+
+	// Digest is the digest to be signed.
+	Digest []byte `json:"digest"`
+}
+
+// ExternalSignResponse is the JSON response of the external signing backends.
+type ExternalSignResponse struct {
+	// Signature is the raw signature in the format of RFC 8152 section 8.
+	Signature []byte `json:"signature"`
+}
+
+// HTTPSigner signs by posting an ExternalSignRequest to a signing service,
+// which responds with an ExternalSignResponse.
+type HTTPSigner struct {
+	// URL is the endpoint of the signing service.
+	URL string
+
+	// KeyID identifies the key in the signing service if present.
+	KeyID string
+
+	// Client is the HTTP client for the signing service.
+	// If not present, http.DefaultClient is used.
+	Client *http.Client
+}
+
+// Sign signs the digest with the algorithm.
+func (s *HTTPSigner) Sign(ctx context.Context, alg cose.Algorithm, digest []byte) ([]byte, error) {
+	body, err := json.Marshal(newExternalSignRequest(s.KeyID, alg, digest))
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %q: unexpected status: %s", req.Method, req.URL, resp.Status)
+	}
+	return decodeExternalSignResponse(resp.Body)
+}
+
+// CommandSigner signs by running a command, which reads an
+// ExternalSignRequest from the standard input, and writes an
+// ExternalSignResponse to the standard output.
+type CommandSigner struct {
+	// Command is the command with arguments.
+	Command []string
+
+	// KeyID identifies the key to the command if present.
+	KeyID string
+}
+
+// Sign signs the digest with the algorithm.
+func (s *CommandSigner) Sign(ctx context.Context, alg cose.Algorithm, digest []byte) ([]byte, error) {
+	if len(s.Command) == 0 {
+		return nil, errors.New("missing signing command")
+	}
+	req, err := json.Marshal(newExternalSignRequest(s.KeyID, alg, digest))
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, s.Command[0], s.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(req)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if stderr.Len() > 0 {
+			return nil, fmt.Errorf("signing command %s: %w: %s", s.Command[0], err, bytes.TrimSpace(stderr.Bytes()))
+		}
+		return nil, fmt.Errorf("signing command %s: %w", s.Command[0], err)
+	}
+	return decodeExternalSignResponse(bytes.NewReader(out))
+}
+
+// newExternalSignRequest creates the request to the external signing backend.
+func newExternalSignRequest(keyID string, alg cose.Algorithm, digest []byte) *ExternalSignRequest {
+	return &ExternalSignRequest{
+		KeyID:     keyID,
+		Algorithm: alg.String(),
+		Digest:    digest,
+	}
+}
+
+// decodeExternalSignResponse decodes the response of the external signing
+// backend, and returns the signature.
+func decodeExternalSignResponse(r io.Reader) ([]byte, error) {
+	var resp ExternalSignResponse
+	if err := json.NewDecoder(io.LimitReader(r, maxExternalSignResponseSize)).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("invalid signing response: %w", err)
+	}
+	if len(resp.Signature) == 0 {
+		return nil, errors.New("invalid signing response: missing signature")
+	}
+	return resp.Signature, nil
+}
+
+// contextSigner adapts an external signer to a COSE signer within the
+// context of a signing operation.
+type contextSigner struct {
+	ctx    context.Context
+	alg    cose.Algorithm
+	signer ExternalSigner
+}
+
+// Algorithm returns the signing algorithm.
+func (s *contextSigner) Algorithm() cose.Algorithm {
+	return s.alg
+}
+
+// Sign signs the digest with the external signer.
+func (s *contextSigner) Sign(_ io.Reader, digest []byte) ([]byte, error) {
+	return s.signer.Sign(s.ctx, s.alg, digest)
+}
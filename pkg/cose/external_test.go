@@ -0,0 +1,112 @@
+package cose
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/notaryproject/notation-go"
+	"github.com/veraison/go-cose"
+)
+
+func TestSignWithExternalSigner(t *testing.T) {
+	// start signing service
+	key, cert, err := generateKeyCertPair()
+	if err != nil {
+		t.Fatalf("generateKeyCertPair() error = %v", err)
+	}
+	server := newTestSigningService(t, key)
+	defer server.Close()
+
+	// sign with the signing service
+	s, err := NewSignerWithExternalSigner(cose.AlgorithmPS256, &HTTPSigner{
+		URL:   server.URL,
+		KeyID: "key-1",
+	}, []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatalf("NewSignerWithExternalSigner() error = %v", err)
+	}
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	sig, err := s.Sign(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	// verify signature
+	v := NewVerifier()
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	v.VerifyOptions.Roots = roots
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+}
+
+func TestSignWithExternalSignerMismatch(t *testing.T) {
+	// the signing service signs with a key other than the key of the cert
+	key, _, err := generateKeyCertPair()
+	if err != nil {
+		t.Fatalf("generateKeyCertPair() error = %v", err)
+	}
+	_, cert, err := generateKeyCertPair()
+	if err != nil {
+		t.Fatalf("generateKeyCertPair() error = %v", err)
+	}
+	server := newTestSigningService(t, key)
+	defer server.Close()
+
+	s, err := NewSignerWithExternalSigner(cose.AlgorithmPS256, &HTTPSigner{
+		URL: server.URL,
+	}, []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatalf("NewSignerWithExternalSigner() error = %v", err)
+	}
+	desc, sOpts := generateSigningContent(nil)
+	if _, err := s.Sign(context.Background(), desc, sOpts); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Sign() error = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	// incompatible algorithm
+	if _, err := NewSignerWithExternalSigner(cose.AlgorithmES256, &HTTPSigner{
+		URL: server.URL,
+	}, []*x509.Certificate{cert}); !errors.Is(err, ErrAlgorithmMismatch) {
+		t.Errorf("NewSignerWithExternalSigner() error = %v, want %v", err, ErrAlgorithmMismatch)
+	}
+}
+
+// newTestSigningService starts a local stand-in of a signing service holding
+// the key.
+func newTestSigningService(t *testing.T, key crypto.Signer) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ExternalSignRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		alg, err := ParseAlgorithm(req.Algorithm)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		signer, err := cose.NewSigner(alg, key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		sig, err := signer.Sign(rand.Reader, req.Digest)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(&ExternalSignResponse{Signature: sig}); err != nil {
+			t.Errorf("Encode() error = %v", err)
+		}
+	}))
+}
@@ -0,0 +1,166 @@
+package cose
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"regexp"
+
+	"github.com/microsoft/notation-cose/pkg/trustpolicy"
+)
+
+// IdentityConstraintOptions lists the acceptable identities of the signing
+// certificate. Empty fields impose no constraint.
+type IdentityConstraintOptions struct {
+	// Subject is the exact subject DN of the signing certificate in the
+	// string representation of RFC 4514, for example,
+	// `C=US, O=Example\, Inc., CN=signer`. The attributes are matched
+	// regardless of their order.
+	Subject string
+
+	// DNSNames requires a DNS name in the SAN to match any of the patterns.
+	// The patterns are anchored implicitly, and match the whole value.
+	DNSNames []string
+
+	// URIs requires a URI in the SAN to match any of the patterns.
+	// The patterns are anchored implicitly, and match the whole value.
+	URIs []string
+
+	// EmailAddresses requires an email address in the SAN to match any of the
+	// patterns.
+	// The patterns are anchored implicitly, and match the whole value.
+	EmailAddresses []string
+
+	// ExtKeyUsages lists the extended key usages required in the signing
+	// certificate, for example, `x509.ExtKeyUsageCodeSigning`.
+	// A certificate with `x509.ExtKeyUsageAny` satisfies any usage.
+	ExtKeyUsages []x509.ExtKeyUsage
+
+	// Policies lists the certificate policy OIDs required in the signing
+	// certificate.
+	Policies []asn1.ObjectIdentifier
+}
+
+// IdentityConstraints pins the acceptable identities of the signing
+// certificate. It is created by NewIdentityConstraints.
+type IdentityConstraints struct {
+	opts           IdentityConstraintOptions
+	subject        *trustpolicy.Identity
+	dnsNames       []*regexp.Regexp
+	uris           []*regexp.Regexp
+	emailAddresses []*regexp.Regexp
+}
+
+// NewIdentityConstraints creates the identity constraints from the options.
+// The subject DN is parsed, and the SAN patterns are anchored and compiled
+// once.
+func NewIdentityConstraints(opts IdentityConstraintOptions) (*IdentityConstraints, error) {
+	c := &IdentityConstraints{
+		opts: opts,
+	}
+	if opts.Subject != "" {
+		subject, err := trustpolicy.ParseIdentity(trustpolicy.IdentitySubject + ": " + opts.Subject)
+		if err != nil {
+			return nil, fmt.Errorf("invalid identity constraints: subject: %w", err)
+		}
+		c.subject = subject
+	}
+	var err error
+	if c.dnsNames, err = compileAnchored(opts.DNSNames); err != nil {
+		return nil, fmt.Errorf("invalid identity constraints: SAN DNS names: %w", err)
+	}
+	if c.uris, err = compileAnchored(opts.URIs); err != nil {
+		return nil, fmt.Errorf("invalid identity constraints: SAN URIs: %w", err)
+	}
+	if c.emailAddresses, err = compileAnchored(opts.EmailAddresses); err != nil {
+		return nil, fmt.Errorf("invalid identity constraints: SAN email addresses: %w", err)
+	}
+	return c, nil
+}
+
+// compileAnchored compiles the patterns anchored to match the whole value.
+func compileAnchored(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// Check checks the signing certificate against the constraints, and returns
+// an IdentityError listing all the failed constraints.
+func (c *IdentityConstraints) Check(cert *x509.Certificate) error {
+	var violations []string
+	if c.subject != nil && !c.subject.Match(cert) {
+		violations = append(violations, fmt.Sprintf("subject %q does not match %q", cert.Subject, c.opts.Subject))
+	}
+	if len(c.dnsNames) > 0 && !matchAny(c.dnsNames, cert.DNSNames) {
+		violations = append(violations, fmt.Sprintf("SAN DNS names %q do not match %q", cert.DNSNames, c.opts.DNSNames))
+	}
+	if len(c.uris) > 0 {
+		uris := make([]string, 0, len(cert.URIs))
+		for _, uri := range cert.URIs {
+			uris = append(uris, uri.String())
+		}
+		if !matchAny(c.uris, uris) {
+			violations = append(violations, fmt.Sprintf("SAN URIs %q do not match %q", uris, c.opts.URIs))
+		}
+	}
+	if len(c.emailAddresses) > 0 && !matchAny(c.emailAddresses, cert.EmailAddresses) {
+		violations = append(violations, fmt.Sprintf("SAN email addresses %q do not match %q", cert.EmailAddresses, c.opts.EmailAddresses))
+	}
+	for _, usage := range c.opts.ExtKeyUsages {
+		if !hasExtKeyUsage(cert, usage) {
+			violations = append(violations, fmt.Sprintf("missing extended key usage %v", usage))
+		}
+	}
+	for _, policy := range c.opts.Policies {
+		if !hasPolicy(cert, policy) {
+			violations = append(violations, fmt.Sprintf("missing certificate policy %v", policy))
+		}
+	}
+	if len(violations) > 0 {
+		return &IdentityError{
+			Certificate: cert,
+			Violations:  violations,
+		}
+	}
+	return nil
+}
+
+// matchAny reports whether any of the values matches any of the anchored
+// patterns.
+func matchAny(patterns []*regexp.Regexp, values []string) bool {
+	for _, pattern := range patterns {
+		for _, value := range values {
+			if pattern.MatchString(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasExtKeyUsage reports whether the certificate has the extended key usage.
+func hasExtKeyUsage(cert *x509.Certificate, usage x509.ExtKeyUsage) bool {
+	for _, u := range cert.ExtKeyUsage {
+		if u == usage || u == x509.ExtKeyUsageAny {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPolicy reports whether the certificate asserts the certificate policy.
+func hasPolicy(cert *x509.Certificate, policy asn1.ObjectIdentifier) bool {
+	for _, p := range cert.PolicyIdentifiers {
+		if p.Equal(policy) {
+			return true
+		}
+	}
+	return false
+}
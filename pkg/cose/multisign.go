@@ -0,0 +1,450 @@
+package cose
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/microsoft/notation-cose/pkg/trustpolicy"
+	"github.com/notaryproject/notation-go"
+	artifactspec "github.com/oras-project/artifacts-spec/specs-go/v1"
+	"github.com/veraison/go-cose"
+)
+
+// ThresholdPolicy requires a COSE_Sign envelope to be signed by at least
+// Threshold of the independent signing parties, each of which is identified
+// by a trust store in `Verifier.TrustStores`. A signature counts toward at
+// most one trust store, and each trust store is counted at most once.
+// Signatures of the same signing key are counted once, and the trust stores
+// must not share keys so that a signer cannot satisfy several of them.
+type ThresholdPolicy struct {
+	// TrustStores lists the names of the trust stores of the signing parties.
+	TrustStores []string
+
+	// Threshold is the minimum number of the trust stores to be satisfied by
+	// the signatures.
+	Threshold int
+}
+
+// Validate validates the threshold policy against the trust stores of the
+// verifier.
+func (p *ThresholdPolicy) Validate(trustStores map[string][]*x509.Certificate) error {
+	if p.Threshold < 1 || p.Threshold > len(p.TrustStores) {
+		return fmt.Errorf("threshold policy: threshold %d out of range [1, %d]", p.Threshold, len(p.TrustStores))
+	}
+	seen := make(map[string]bool, len(p.TrustStores))
+	for _, name := range p.TrustStores {
+		if seen[name] {
+			return fmt.Errorf("threshold policy: duplicate trust store %q", name)
+		}
+		seen[name] = true
+		if _, ok := trustStores[name]; !ok {
+			return fmt.Errorf("threshold policy: trust store %q not found", name)
+		}
+	}
+	owners := make(map[string]string)
+	for _, name := range p.TrustStores {
+		for _, cert := range trustStores[name] {
+			key := string(cert.RawSubjectPublicKeyInfo)
+			if owner, ok := owners[key]; ok && owner != name {
+				return fmt.Errorf("threshold policy: trust stores %q and %q overlap: %s", owner, name, cert.Subject)
+			}
+			owners[key] = name
+		}
+	}
+	return nil
+}
+
+// signMessagePrefix is the CBOR prefix of the tagged COSE_Sign object.
+var signMessagePrefix = []byte{
+	0xd8, 0x62, // #6.98
+	0x84, // array of length 4
+}
+
+// IsMultiSigner reports whether the envelope is a COSE_Sign envelope, which
+// is verified by `Verifier.VerifyMulti`, rather than a COSE_Sign1 signature.
+func IsMultiSigner(envelope []byte) bool {
+	return bytes.HasPrefix(envelope, signMessagePrefix)
+}
+
+// MultiVerificationResult contains the verified descriptor and the results of
+// the signatures of a verified COSE_Sign envelope.
+type MultiVerificationResult struct {
+	// Descriptor is the verified descriptor of the signed artifact.
+	Descriptor notation.Descriptor
+
+	// Signatures contains the results of the verified signatures. With the
+	// threshold policy, only the signatures counted toward the threshold are
+	// present, and their results carry the name of the satisfied trust store.
+	Signatures []*VerificationResult
+
+	// Errors contains the failures of the signatures which cannot be verified
+	// against any trust store of the threshold policy.
+	Errors []error
+}
+
+// MarshalJSON encodes the verification result in JSON for logging and
+// auditing.
+func (r *MultiVerificationResult) MarshalJSON() ([]byte, error) {
+	type result struct {
+		Descriptor notation.Descriptor   `json:"descriptor"`
+		Signatures []*VerificationResult `json:"signatures"`
+		Errors     []string              `json:"errors,omitempty"`
+	}
+	out := result{
+		Descriptor: r.Descriptor,
+		Signatures: r.Signatures,
+	}
+	for _, err := range r.Errors {
+		out.Errors = append(out.Errors, err.Error())
+	}
+	return json.Marshal(out)
+}
+
+// SignMulti signs the artifact described by its descriptor, and returns a
+// COSE_Sign envelope with the signature of the signer. Other signers append
+// their signatures to the envelope by `AppendSignature`.
+// Reference: RFC 9052 4.1 Signing with One or More Signers.
+func (s *Signer) SignMulti(ctx context.Context, desc notation.Descriptor, opts notation.SignOptions) ([]byte, error) {
+	payload, err := json.Marshal(desc)
+	if err != nil {
+		return nil, err
+	}
+	msg := cose.NewSignMessage()
+	msg.Payload = payload
+	msg.Headers.Protected = cose.ProtectedHeader{
+		cose.HeaderLabelCritical: []interface{}{
+			cose.HeaderLabelContentType,
+		},
+		cose.HeaderLabelContentType: artifactspec.MediaTypeDescriptor,
+	}
+	msg.Headers.RawProtected, err = msg.Headers.MarshalProtected()
+	if err != nil {
+		return nil, err
+	}
+	return s.appendSignature(ctx, msg, opts)
+}
+
+// AppendSignature appends the signature of the signer to the COSE_Sign
+// envelope of the artifact described by its descriptor. The existing
+// signatures are preserved as they sign the same body protected header and
+// payload.
+func (s *Signer) AppendSignature(ctx context.Context, envelope []byte, desc notation.Descriptor, opts notation.SignOptions) ([]byte, error) {
+	msg := &cose.SignMessage{}
+	if err := msg.UnmarshalCBOR(envelope); err != nil {
+		return nil, &EnvelopeError{Err: err}
+	}
+	if msg.Payload == nil {
+		return nil, &EnvelopeError{Err: errors.New("missing payload")}
+	}
+	var signed notation.Descriptor
+	if err := json.Unmarshal(msg.Payload, &signed); err != nil {
+		return nil, &EnvelopeError{Err: fmt.Errorf("invalid payload: %w", err)}
+	}
+	if !signed.Equal(desc) {
+		return nil, fmt.Errorf("envelope signs another artifact: %s", signed.Digest)
+	}
+	for _, sig := range msg.Signatures {
+		if bytes.Equal(leafCertificate(sig.Headers.Protected), s.certChain[0]) {
+			return nil, errors.New("envelope already signed by the signing certificate")
+		}
+	}
+	return s.appendSignature(ctx, msg, opts)
+}
+
+// appendSignature signs the COSE_Sign message, appends the signature, and
+// encodes the message.
+func (s *Signer) appendSignature(ctx context.Context, msg *cose.SignMessage, opts notation.SignOptions) ([]byte, error) {
+	if s.base == nil && s.external == nil {
+		return nil, errors.New("signer has no signing key")
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	// populate the headers of the signature
+	critical, err := s.criticalHeaders()
+	if err != nil {
+		return nil, err
+	}
+	sig := cose.NewSignature()
+	sig.Headers.Protected = cose.ProtectedHeader{
+		cose.HeaderLabelAlgorithm: s.alg,
+		cose.HeaderLabelX5Chain:   s.certChain,
+		"signingtime":             now(s.Clock),
+	}
+	if len(critical) > 0 {
+		sig.Headers.Protected[cose.HeaderLabelCritical] = critical
+	}
+	if !opts.Expiry.IsZero() {
+		sig.Headers.Protected["exp"] = opts.Expiry.Unix()
+	}
+	if err := mergeHeader(sig.Headers.Protected, s.ProtectedHeaders, reservedProtectedHeaders); err != nil {
+		return nil, err
+	}
+	if err := mergeHeader(sig.Headers.Unprotected, s.UnprotectedHeaders, reservedUnprotectedHeaders); err != nil {
+		return nil, err
+	}
+
+	// generate COSE signature
+	var signer cose.Signer = s.base
+	if s.external != nil {
+		signer = &contextSigner{
+			ctx:    ctx,
+			alg:    s.alg,
+			signer: s.external,
+		}
+	}
+	if err := sig.Sign(rand.Reader, signer, msg.Headers.RawProtected, msg.Payload, nil); err != nil {
+		if s.external != nil {
+			return nil, fmt.Errorf("external signer failed: %w", err)
+		}
+		return nil, err
+	}
+	if s.external != nil {
+		verifier, err := cose.NewVerifier(s.alg, s.certs[0].PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		if err := sig.Verify(verifier, msg.Headers.RawProtected, msg.Payload, nil); err != nil {
+			return nil, fmt.Errorf("external signer returned a signature not matching the signing certificate: %w", ErrInvalidSignature)
+		}
+	}
+	if err := s.stampSignature(ctx, sig.Signature, sig.Headers.Unprotected, opts); err != nil {
+		return nil, err
+	}
+
+	// encode in CBOR
+	msg.Signatures = append(msg.Signatures, sig)
+	return msg.MarshalCBOR()
+}
+
+// VerifyMulti verifies the COSE_Sign envelope and returns the verification
+// result of the signatures.
+// Without the threshold policy, every signature is verified as a COSE_Sign1
+// signature would be. With the threshold policy, the signatures are matched
+// with the trust stores of the policy, and the verification succeeds if at
+// least the threshold number of trust stores are satisfied.
+func (v *Verifier) VerifyMulti(ctx context.Context, envelope []byte, opts notation.VerifyOptions) (*MultiVerificationResult, error) {
+	return v.VerifyMultiWithOptions(ctx, envelope, VerifyOptions{
+		VerifyOptions: opts,
+	})
+}
+
+// VerifyMultiWithOptions verifies the COSE_Sign envelope with the options of
+// this call, and returns the verification result of the signatures.
+func (v *Verifier) VerifyMultiWithOptions(ctx context.Context, envelope []byte, opts VerifyOptions) (*MultiVerificationResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	result, err := v.withOptions(opts).verifyMulti(ctx, envelope)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.matchDescriptor(result.Descriptor); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// verifyMulti verifies the COSE_Sign envelope.
+func (v *Verifier) verifyMulti(ctx context.Context, envelope []byte) (*MultiVerificationResult, error) {
+	// unpack envelope
+	msg := &cose.SignMessage{}
+	if err := msg.UnmarshalCBOR(envelope); err != nil {
+		return nil, &EnvelopeError{Err: err}
+	}
+	if msg.Payload == nil {
+		return nil, &EnvelopeError{Err: errors.New("missing payload")}
+	}
+	result := &MultiVerificationResult{}
+	if err := json.Unmarshal(msg.Payload, &result.Descriptor); err != nil {
+		return nil, &EnvelopeError{Err: fmt.Errorf("invalid payload: %w", err)}
+	}
+	if err := v.verifyCritical(msg.Headers.Protected); err != nil {
+		return nil, err
+	}
+	var policy *trustpolicy.Policy
+	if v.TrustPolicy != nil {
+		var err error
+		if policy, err = v.matchTrustPolicy(); err != nil {
+			return nil, err
+		}
+	}
+
+	// verify every signature without the threshold policy
+	if v.Threshold == nil {
+		for _, sig := range msg.Signatures {
+			sigResult, err := v.verifySignature(ctx, msg, sig, policy, "")
+			if err != nil {
+				return nil, err
+			}
+			result.Signatures = append(result.Signatures, sigResult)
+		}
+		return result.finalize(), nil
+	}
+
+	// verify the signatures against each trust store of the threshold policy
+	if err := v.Threshold.Validate(v.TrustStores); err != nil {
+		return nil, err
+	}
+	stores := v.Threshold.TrustStores
+	if policy != nil {
+		// the threshold trust stores are restricted to the trust policy
+		for _, name := range stores {
+			if !containsString(policy.TrustStores, name) {
+				return nil, fmt.Errorf("%w: threshold trust store %q not allowed by trust policy %q", ErrUntrustedSigner, name, policy.Name)
+			}
+		}
+	}
+	candidates := make([][]*VerificationResult, len(msg.Signatures))
+	signers := make(map[string]int)
+	for i, sig := range msg.Signatures {
+		candidates[i] = make([]*VerificationResult, len(stores))
+		var signer *x509.Certificate
+		var sigErr error
+		for j, name := range stores {
+			sigResult, err := v.verifySignature(ctx, msg, sig, policy, name)
+			if err != nil {
+				sigErr = err
+				continue
+			}
+			candidates[i][j] = sigResult
+			signer = sigResult.Signer()
+		}
+		if signer == nil {
+			result.Errors = append(result.Errors, fmt.Errorf("signature %d: %w", i, sigErr))
+			continue
+		}
+
+		// count the signatures of the same signing key once, even with
+		// different certificates
+		key := string(signer.RawSubjectPublicKeyInfo)
+		if first, ok := signers[key]; ok {
+			candidates[i] = nil
+			result.Errors = append(result.Errors, fmt.Errorf("signature %d: signing key already counted by signature %d", i, first))
+			continue
+		}
+		signers[key] = i
+	}
+
+	// count each signature and each trust store at most once
+	for i, j := range matchSignatures(candidates, len(stores)) {
+		if j >= 0 {
+			result.Signatures = append(result.Signatures, candidates[i][j])
+		}
+	}
+	if len(result.Signatures) < v.Threshold.Threshold {
+		return nil, &ThresholdError{
+			Threshold: v.Threshold.Threshold,
+			Satisfied: len(result.Signatures),
+			Errs:      result.Errors,
+		}
+	}
+	return result.finalize(), nil
+}
+
+// verifySignature verifies a signature of the COSE_Sign message, and returns
+// the verification result. The signing certificate chain is verified against
+// the named trust store if present, which must be one of the trust stores of
+// the trust policy. Against a trust store, the authenticity checks are
+// enforced at any verification level.
+func (v *Verifier) verifySignature(ctx context.Context, msg *cose.SignMessage, sig *cose.Signature, policy *trustpolicy.Policy, trustStore string) (*VerificationResult, error) {
+	result := &VerificationResult{
+		UnprotectedHeaders:  sig.Headers.Unprotected,
+		TrustPolicy:         policy,
+		TrustStore:          trustStore,
+		enforceAuthenticity: trustStore != "",
+	}
+	sv := v
+	if trustStore != "" {
+		roots := x509.NewCertPool()
+		for _, cert := range v.TrustStores[trustStore] {
+			roots.AddCert(cert)
+		}
+		verifier := *v
+		verifier.VerifyOptions.Roots = roots
+		sv = &verifier
+		if policy != nil {
+			if !containsString(policy.TrustStores, trustStore) {
+				return nil, fmt.Errorf("%w: trust store %q not allowed by trust policy %q", ErrUntrustedSigner, trustStore, policy.Name)
+			}
+			storePolicy := *policy
+			storePolicy.TrustStores = []string{trustStore}
+			result.TrustPolicy = &storePolicy
+		}
+	}
+	verifier, err := sv.verifySigner(ctx, &sig.Headers, sig.Signature, result)
+	if err != nil {
+		return nil, err
+	}
+	if err := sig.Verify(verifier, msg.Headers.RawProtected, msg.Payload, nil); err != nil {
+		return nil, &SignatureError{Err: err}
+	}
+	if err := sv.verifyAttributes(sig.Headers.Protected, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// finalize populates the descriptor in the results of the signatures.
+func (r *MultiVerificationResult) finalize() *MultiVerificationResult {
+	for _, sigResult := range r.Signatures {
+		sigResult.Descriptor = r.Descriptor
+	}
+	return r
+}
+
+// matchSignatures finds a maximum matching between the signatures and the
+// trust stores by augmenting paths, so that a signature chaining to several
+// trust stores does not take the only trust store of another signature.
+// It returns the index of the matched trust store of each signature, or -1 if
+// the signature is not matched.
+func matchSignatures(candidates [][]*VerificationResult, storeCount int) []int {
+	storeMatch := make([]int, storeCount)
+	for j := range storeMatch {
+		storeMatch[j] = -1
+	}
+	var augment func(i int, visited []bool) bool
+	augment = func(i int, visited []bool) bool {
+		for j, result := range candidates[i] {
+			if result == nil || visited[j] {
+				continue
+			}
+			visited[j] = true
+			if storeMatch[j] < 0 || augment(storeMatch[j], visited) {
+				storeMatch[j] = i
+				return true
+			}
+		}
+		return false
+	}
+	for i := range candidates {
+		augment(i, make([]bool, storeCount))
+	}
+
+	sigMatch := make([]int, len(candidates))
+	for i := range sigMatch {
+		sigMatch[i] = -1
+	}
+	for j, i := range storeMatch {
+		if i >= 0 {
+			sigMatch[i] = j
+		}
+	}
+	return sigMatch
+}
+
+// containsString reports whether the string is in the list.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,376 @@
+package cose
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/microsoft/notation-cose/pkg/trustpolicy"
+	"github.com/notaryproject/notation-go"
+)
+
+func TestSignMultiWithThreshold(t *testing.T) {
+	// sign by the build system and append the signature of the release manager
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	buildSigner, buildCert := newTestSigner(t)
+	releaseSigner, releaseCert := newTestSigner(t)
+	envelope, err := buildSigner.SignMulti(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("SignMulti() error = %v", err)
+	}
+	envelope, err = releaseSigner.AppendSignature(ctx, envelope, desc, sOpts)
+	if err != nil {
+		t.Fatalf("AppendSignature() error = %v", err)
+	}
+	if _, err := releaseSigner.AppendSignature(ctx, envelope, desc, sOpts); err == nil {
+		t.Error("AppendSignature() error = nil, want error for duplicate signer")
+	}
+	otherDesc := desc
+	otherDesc.Size++
+	if _, err := buildSigner.AppendSignature(ctx, envelope, otherDesc, sOpts); err == nil {
+		t.Error("AppendSignature() error = nil, want error for another artifact")
+	}
+
+	// verify both signatures
+	_, otherCert := newTestSigner(t)
+	v := NewVerifier()
+	v.TrustStores = map[string][]*x509.Certificate{
+		"build":   {buildCert},
+		"release": {releaseCert},
+		"audit":   {otherCert},
+	}
+	v.Threshold = &ThresholdPolicy{
+		TrustStores: []string{"build", "release", "audit"},
+		Threshold:   2,
+	}
+	result, err := v.VerifyMulti(ctx, envelope, notation.VerifyOptions{})
+	if err != nil {
+		t.Fatalf("VerifyMulti() error = %v", err)
+	}
+	if result.Descriptor.Digest != desc.Digest {
+		t.Errorf("VerifyMulti() Digest = %v, want %v", result.Descriptor.Digest, desc.Digest)
+	}
+	got := make(map[string]bool)
+	for _, sigResult := range result.Signatures {
+		got[sigResult.TrustStore] = true
+		if sigResult.Descriptor.Digest != desc.Digest {
+			t.Errorf("VerifyMulti() signature Digest = %v, want %v", sigResult.Descriptor.Digest, desc.Digest)
+		}
+	}
+	if len(got) != 2 || !got["build"] || !got["release"] {
+		t.Errorf("VerifyMulti() trust stores = %v, want build and release", got)
+	}
+
+	// threshold not met
+	v.Threshold.Threshold = 3
+	if _, err := v.VerifyMulti(ctx, envelope, notation.VerifyOptions{}); !errors.Is(err, ErrUntrustedSigner) {
+		t.Errorf("VerifyMulti() error = %v, want %v", err, ErrUntrustedSigner)
+	}
+
+	// all signatures are verified without the threshold policy
+	v.Threshold = nil
+	roots := x509.NewCertPool()
+	roots.AddCert(buildCert)
+	v.VerifyOptions.Roots = roots
+	if _, err := v.VerifyMulti(ctx, envelope, notation.VerifyOptions{}); !errors.Is(err, ErrUntrustedSigner) {
+		t.Errorf("VerifyMulti() error = %v, want %v", err, ErrUntrustedSigner)
+	}
+	roots.AddCert(releaseCert)
+	if result, err := v.VerifyMulti(ctx, envelope, notation.VerifyOptions{}); err != nil {
+		t.Errorf("VerifyMulti() error = %v", err)
+	} else if len(result.Signatures) != 2 {
+		t.Errorf("VerifyMulti() got %d signatures, want 2", len(result.Signatures))
+	}
+}
+
+func TestVerifyMultiMatching(t *testing.T) {
+	// the first signer is trusted by both trust stores by cross-signing, and
+	// the second signer only by the first trust store
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	signer1, rootA, rootB := newCrossSignedSigner(t)
+	signer2, cert2 := newTestSigner(t)
+	envelope, err := signer1.SignMulti(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("SignMulti() error = %v", err)
+	}
+	envelope, err = signer2.AppendSignature(ctx, envelope, desc, sOpts)
+	if err != nil {
+		t.Fatalf("AppendSignature() error = %v", err)
+	}
+
+	v := NewVerifier()
+	v.TrustStores = map[string][]*x509.Certificate{
+		"a": {rootA, cert2},
+		"b": {rootB},
+	}
+	v.Threshold = &ThresholdPolicy{
+		TrustStores: []string{"a", "b"},
+		Threshold:   2,
+	}
+	result, err := v.VerifyMulti(ctx, envelope, notation.VerifyOptions{})
+	if err != nil {
+		t.Fatalf("VerifyMulti() error = %v", err)
+	}
+	for _, sigResult := range result.Signatures {
+		want := "b"
+		if sigResult.Signer().Equal(cert2) {
+			want = "a"
+		}
+		if sigResult.TrustStore != want {
+			t.Errorf("VerifyMulti() trust store of %v = %v, want %v", sigResult.Signer().Subject, sigResult.TrustStore, want)
+		}
+	}
+
+	// invalid threshold policy
+	v.Threshold.TrustStores = []string{"a", "c"}
+	if _, err := v.VerifyMulti(ctx, envelope, notation.VerifyOptions{}); err == nil {
+		t.Error("VerifyMulti() error = nil, want error for unknown trust store")
+	}
+}
+
+func TestVerifyMultiDuplicateSigner(t *testing.T) {
+	// a single key signs twice with the certificates issued by two CAs
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	keys := newTestKeys(t, 3)
+	rootA := newTestCertificate(t, "root A", keys[0], nil, nil)
+	rootB := newTestCertificate(t, "root B", keys[1], nil, nil)
+	signerA, err := NewSigner(keys[2], []*x509.Certificate{newTestCertificate(t, "leaf", keys[2], rootA, keys[0])})
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	signerB, err := NewSigner(keys[2], []*x509.Certificate{newTestCertificate(t, "leaf", keys[2], rootB, keys[1])})
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	envelope, err := signerA.SignMulti(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("SignMulti() error = %v", err)
+	}
+	envelope, err = signerB.AppendSignature(ctx, envelope, desc, sOpts)
+	if err != nil {
+		t.Fatalf("AppendSignature() error = %v", err)
+	}
+
+	// the signatures of the same key are counted once
+	v := NewVerifier()
+	v.TrustStores = map[string][]*x509.Certificate{
+		"a": {rootA},
+		"b": {rootB},
+		"c": {rootA, rootB},
+	}
+	v.Threshold = &ThresholdPolicy{
+		TrustStores: []string{"a", "b"},
+		Threshold:   2,
+	}
+	if _, err := v.VerifyMulti(ctx, envelope, notation.VerifyOptions{}); !errors.Is(err, ErrUntrustedSigner) {
+		t.Errorf("VerifyMulti() error = %v, want %v", err, ErrUntrustedSigner)
+	}
+	v.Threshold.Threshold = 1
+	result, err := v.VerifyMulti(ctx, envelope, notation.VerifyOptions{})
+	if err != nil {
+		t.Fatalf("VerifyMulti() error = %v", err)
+	}
+	if len(result.Signatures) != 1 || len(result.Errors) != 1 {
+		t.Errorf("VerifyMulti() got %d signatures and %d errors, want 1 and 1", len(result.Signatures), len(result.Errors))
+	}
+
+	// the trust stores must not overlap
+	v.Threshold.TrustStores = []string{"a", "c"}
+	if err := v.Threshold.Validate(v.TrustStores); err == nil {
+		t.Error("ThresholdPolicy.Validate() error = nil, want error for overlapping trust stores")
+	}
+	if _, err := v.VerifyMulti(ctx, envelope, notation.VerifyOptions{}); err == nil {
+		t.Error("VerifyMulti() error = nil, want error for overlapping trust stores")
+	}
+}
+
+func TestVerifyMultiWithTrustPolicy(t *testing.T) {
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	buildSigner, buildCert := newTestSigner(t)
+	releaseSigner, releaseCert := newTestSigner(t)
+	envelope, err := buildSigner.SignMulti(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("SignMulti() error = %v", err)
+	}
+	envelope, err = releaseSigner.AppendSignature(ctx, envelope, desc, sOpts)
+	if err != nil {
+		t.Fatalf("AppendSignature() error = %v", err)
+	}
+
+	v := NewVerifier()
+	v.TrustPolicy = &trustpolicy.Document{
+		Version: trustpolicy.Version,
+		TrustPolicies: []trustpolicy.Policy{
+			{
+				Name:                  "test",
+				RegistryScopes:        []string{"*"},
+				SignatureVerification: trustpolicy.LevelStrict,
+				TrustStores:           []string{"build"},
+				TrustedIdentities:     []string{"*"},
+			},
+		},
+	}
+	v.TrustStores = map[string][]*x509.Certificate{
+		"build":   {buildCert},
+		"release": {releaseCert},
+	}
+	v.Threshold = &ThresholdPolicy{
+		TrustStores: []string{"build", "release"},
+		Threshold:   2,
+	}
+	opts := VerifyOptions{ArtifactReference: "test.registry.io/test:example"}
+
+	// the threshold trust stores are not allowed by the trust policy
+	if _, err := v.VerifyMultiWithOptions(ctx, envelope, opts); !errors.Is(err, ErrUntrustedSigner) {
+		t.Errorf("VerifyMultiWithOptions() error = %v, want %v", err, ErrUntrustedSigner)
+	}
+
+	v.TrustPolicy.TrustPolicies[0].TrustStores = []string{"build", "release"}
+	if _, err := v.VerifyMultiWithOptions(ctx, envelope, opts); err != nil {
+		t.Errorf("VerifyMultiWithOptions() error = %v", err)
+	}
+}
+
+func TestVerifyMultiAuditThreshold(t *testing.T) {
+	// the second signer is not trusted by any trust store
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	buildSigner, buildCert := newTestSigner(t)
+	untrustedSigner, _ := newTestSigner(t)
+	_, releaseCert := newTestSigner(t)
+	envelope, err := buildSigner.SignMulti(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("SignMulti() error = %v", err)
+	}
+	envelope, err = untrustedSigner.AppendSignature(ctx, envelope, desc, sOpts)
+	if err != nil {
+		t.Fatalf("AppendSignature() error = %v", err)
+	}
+
+	v := NewVerifier()
+	v.TrustPolicy = &trustpolicy.Document{
+		Version: trustpolicy.Version,
+		TrustPolicies: []trustpolicy.Policy{
+			{
+				Name:                  "test",
+				RegistryScopes:        []string{"*"},
+				SignatureVerification: trustpolicy.LevelAudit,
+				TrustStores:           []string{"build", "release"},
+				TrustedIdentities:     []string{"*"},
+			},
+		},
+	}
+	v.TrustStores = map[string][]*x509.Certificate{
+		"build":   {buildCert},
+		"release": {releaseCert},
+	}
+	v.Threshold = &ThresholdPolicy{
+		TrustStores: []string{"build", "release"},
+		Threshold:   2,
+	}
+	opts := VerifyOptions{ArtifactReference: "test.registry.io/test:example"}
+
+	// untrusted signers do not count toward the threshold at the audit level
+	if _, err := v.VerifyMultiWithOptions(ctx, envelope, opts); !errors.Is(err, ErrUntrustedSigner) {
+		t.Errorf("VerifyMultiWithOptions() error = %v, want %v", err, ErrUntrustedSigner)
+	}
+	v.Threshold.Threshold = 1
+	result, err := v.VerifyMultiWithOptions(ctx, envelope, opts)
+	if err != nil {
+		t.Fatalf("VerifyMultiWithOptions() error = %v", err)
+	}
+	if len(result.Signatures) != 1 || result.Signatures[0].TrustStore != "build" {
+		t.Errorf("VerifyMultiWithOptions() signatures = %v, want the build signature", result.Signatures)
+	}
+}
+
+// newTestSigner creates a signer with a self-signed certificate.
+func newTestSigner(t *testing.T) (*Signer, *x509.Certificate) {
+	key, cert, err := generateKeyCertPair()
+	if err != nil {
+		t.Fatalf("generateKeyCertPair() error = %v", err)
+	}
+	s, err := NewSigner(key, []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	return s, cert
+}
+
+// newCrossSignedSigner creates a signer whose intermediate CA is cross-signed
+// by two root CAs, and returns the root certificates.
+func newCrossSignedSigner(t *testing.T) (*Signer, *x509.Certificate, *x509.Certificate) {
+	keys := newTestKeys(t, 4)
+	rootA := newTestCertificate(t, "root A", keys[0], nil, nil)
+	rootB := newTestCertificate(t, "root B", keys[1], nil, nil)
+	intermediateA := newTestCertificate(t, "intermediate", keys[2], rootA, keys[0])
+	intermediateB := newTestCertificate(t, "intermediate", keys[2], rootB, keys[1])
+	leaf := newTestCertificate(t, "leaf", keys[3], intermediateA, keys[2])
+	s, err := NewSigner(keys[3], []*x509.Certificate{leaf, intermediateA, intermediateB})
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	return s, rootA, rootB
+}
+
+// newTestKeys generates ECDSA test keys.
+func newTestKeys(t *testing.T, n int) []*ecdsa.PrivateKey {
+	keys := make([]*ecdsa.PrivateKey, n)
+	for i := range keys {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("ecdsa.GenerateKey() error = %v", err)
+		}
+		keys[i] = key
+	}
+	return keys
+}
+
+// newTestCertificate issues a test certificate for the key by the parent, or
+// a self-signed CA certificate if the parent is nil. Certificates other than
+// the leaf certificates are CA certificates.
+func newTestCertificate(t *testing.T, name string, key *ecdsa.PrivateKey, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) *x509.Certificate {
+	serialNumber, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
+	if err != nil {
+		t.Fatalf("rand.Int() error = %v", err)
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			CommonName: name,
+		},
+		NotBefore:             now,
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	if name != "leaf" {
+		template.KeyUsage = x509.KeyUsageCertSign
+		template.IsCA = true
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate() error = %v", err)
+	}
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate() error = %v", err)
+	}
+	return cert
+}
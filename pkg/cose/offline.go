@@ -0,0 +1,176 @@
+package cose
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/notaryproject/notation-go"
+	artifactspec "github.com/oras-project/artifacts-spec/specs-go/v1"
+	"github.com/veraison/go-cose"
+)
+
+// SigningRequest is the first phase of a two-phase signing. It carries the
+// bytes to be signed outside of the process, such as in an offline signing
+// ceremony, and is consumed by `Signer.Attach` with the produced signature.
+type SigningRequest struct {
+	// Algorithm is the name of the signing algorithm, such as `PS256`.
+	Algorithm string `json:"algorithm"`
+
+	// Protected is the CBOR encoded protected header.
+	Protected []byte `json:"protected"`
+
+	// Payload is the signed payload.
+	Payload []byte `json:"payload"`
+
+	// Detached indicates that the payload is omitted from the signature.
+	Detached bool `json:"detached,omitempty"`
+
+	// ToBeSigned is the CBOR encoded Sig_structure.
+	// Reference: RFC 9052 4.4 Signing and Verification Process.
+	ToBeSigned []byte `json:"toBeSigned"`
+
+	// Digest is the digest of ToBeSigned by the hash algorithm of the signing
+	// algorithm, which is signed by the raw signing operation. For EdDSA, the
+	// digest is ToBeSigned itself.
+	Digest []byte `json:"digest"`
+}
+
+// NewOfflineSigner creates a signer without access to the signing key of the
+// certificate chain. The signer prepares the signing requests, and attaches
+// the signatures produced elsewhere.
+func NewOfflineSigner(alg cose.Algorithm, certChain []*x509.Certificate) (*Signer, error) {
+	if len(certChain) == 0 {
+		return nil, errors.New("missing signer certificate chain")
+	}
+	if err := validateAlgorithm(alg, certChain[0].PublicKey); err != nil {
+		return nil, err
+	}
+	return &Signer{
+		alg:       alg,
+		certChain: rawCertificates(certChain),
+		certs:     certChain,
+	}, nil
+}
+
+// Prepare prepares the signing request of the artifact described by its
+// descriptor.
+func (s *Signer) Prepare(desc notation.Descriptor, opts notation.SignOptions) (*SigningRequest, error) {
+	payload, err := json.Marshal(desc)
+	if err != nil {
+		return nil, err
+	}
+	return s.prepare(payload, artifactspec.MediaTypeDescriptor, false, opts)
+}
+
+// PrepareBlob prepares the signing request of the arbitrary content, whose
+// signature is generated with the payload detached.
+func (s *Signer) PrepareBlob(content []byte, opts notation.SignOptions) (*SigningRequest, error) {
+	if content == nil {
+		content = []byte{}
+	}
+	return s.prepare(content, MediaTypeBlob, true, opts)
+}
+
+// prepare prepares the signing request of the payload of the specified
+// content type. The signing time and the expiry are fixed in the protected
+// header at this point.
+func (s *Signer) prepare(payload []byte, contentType string, detached bool, opts notation.SignOptions) (*SigningRequest, error) {
+	msg, err := s.newMessage(payload, contentType, opts)
+	if err != nil {
+		return nil, err
+	}
+	protected, err := msg.Headers.MarshalProtected()
+	if err != nil {
+		return nil, err
+	}
+	toBeSigned, err := sigStructure(protected, payload)
+	if err != nil {
+		return nil, err
+	}
+	return &SigningRequest{
+		Algorithm:  s.alg.String(),
+		Protected:  protected,
+		Payload:    payload,
+		Detached:   detached,
+		ToBeSigned: toBeSigned,
+		Digest:     digestToBeSigned(s.alg, toBeSigned),
+	}, nil
+}
+
+// Attach assembles the signature envelope from the signing request and the
+// raw signature in the format of RFC 8152 section 8. The signature is verified
+// against the signing certificate before the envelope is timestamped with the
+// TSA in the options.
+func (s *Signer) Attach(ctx context.Context, req *SigningRequest, signature []byte, opts notation.SignOptions) ([]byte, error) {
+	if req == nil {
+		return nil, errors.New("nil signing request")
+	}
+	if len(signature) == 0 {
+		return nil, errors.New("missing signature")
+	}
+
+	// restore the message
+	var protected cose.ProtectedHeader
+	if err := protected.UnmarshalCBOR(req.Protected); err != nil {
+		return nil, fmt.Errorf("invalid signing request: %w", err)
+	}
+	if alg, err := protected.Algorithm(); err != nil || alg != s.alg {
+		return nil, fmt.Errorf("invalid signing request: algorithm is not %v", s.alg)
+	}
+	if !bytes.Equal(leafCertificate(protected), s.certChain[0]) {
+		return nil, errors.New("invalid signing request: prepared for another signing certificate")
+	}
+	msg := cose.NewSign1Message()
+	msg.Headers.RawProtected = req.Protected
+	msg.Headers.Protected = protected
+	if err := mergeHeader(msg.Headers.Unprotected, s.UnprotectedHeaders, reservedUnprotectedHeaders); err != nil {
+		return nil, err
+	}
+	msg.Payload = req.Payload
+	if msg.Payload == nil {
+		msg.Payload = []byte{}
+	}
+	msg.Signature = signature
+
+	// verify the signature against the signing certificate
+	verifier, err := cose.NewVerifier(s.alg, s.certs[0].PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := msg.Verify(nil, verifier); err != nil {
+		return nil, fmt.Errorf("signature does not match the signing request and the signing certificate: %w", ErrInvalidSignature)
+	}
+	return s.finalize(ctx, msg, req.Detached, opts)
+}
+
+// sigStructure encodes the Sig_structure of COSE_Sign1 without external
+// data.
+// Reference: RFC 9052 4.4 Signing and Verification Process.
+func sigStructure(protected, payload []byte) ([]byte, error) {
+	return cbor.Marshal([]interface{}{
+		"Signature1",               // context
+		cbor.RawMessage(protected), // body_protected
+		[]byte{},                   // external_aad
+		payload,                    // payload
+	})
+}
+
+// leafCertificate returns the first certificate in the x5chain parameter of
+// the protected header.
+func leafCertificate(protected cose.ProtectedHeader) []byte {
+	switch chain := protected[cose.HeaderLabelX5Chain].(type) {
+	case []byte:
+		return chain
+	case []interface{}:
+		if len(chain) > 0 {
+			cert, _ := chain[0].([]byte)
+			return cert
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package cose
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/notaryproject/notation-go"
+	"github.com/veraison/go-cose"
+)
+
+func TestPrepareAndAttach(t *testing.T) {
+	// prepare signing request
+	key, cert, err := generateKeyCertPair()
+	if err != nil {
+		t.Fatalf("generateKeyCertPair() error = %v", err)
+	}
+	s, err := NewOfflineSigner(cose.AlgorithmPS256, []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatalf("NewOfflineSigner() error = %v", err)
+	}
+	desc, sOpts := generateSigningContent(nil)
+	req, err := s.Prepare(desc, sOpts)
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	if _, err := s.Sign(context.Background(), desc, sOpts); err == nil {
+		t.Error("Sign() error = nil, want error for signer without key")
+	}
+
+	// sign offline through the serialized request
+	req = roundTripSigningRequest(t, req)
+	sig := signDigest(t, cose.AlgorithmPS256, key, req.Digest)
+
+	// attach signature
+	ctx := context.Background()
+	envelope, err := s.Attach(ctx, req, sig, notation.SignOptions{})
+	if err != nil {
+		t.Fatalf("Attach() error = %v", err)
+	}
+	v := NewVerifier()
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	v.VerifyOptions.Roots = roots
+	got, err := v.Verify(ctx, envelope, notation.VerifyOptions{})
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if got.Digest != desc.Digest {
+		t.Errorf("Verify() Digest = %v, want %v", got.Digest, desc.Digest)
+	}
+
+	// signature by another key
+	otherKey, _, err := generateKeyCertPair()
+	if err != nil {
+		t.Fatalf("generateKeyCertPair() error = %v", err)
+	}
+	sig = signDigest(t, cose.AlgorithmPS256, otherKey, req.Digest)
+	if _, err := s.Attach(ctx, req, sig, notation.SignOptions{}); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Attach() error = %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestPrepareBlobWithEd25519(t *testing.T) {
+	// prepare signing request
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey() error = %v", err)
+	}
+	cert, err := generateCertificate(key)
+	if err != nil {
+		t.Fatalf("generateCertificate() error = %v", err)
+	}
+	s, err := NewOfflineSigner(cose.AlgorithmEd25519, []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatalf("NewOfflineSigner() error = %v", err)
+	}
+	content := []byte("hello world")
+	_, sOpts := generateSigningContent(nil)
+	req, err := s.PrepareBlob(content, sOpts)
+	if err != nil {
+		t.Fatalf("PrepareBlob() error = %v", err)
+	}
+	if string(req.Digest) != string(req.ToBeSigned) {
+		t.Error("PrepareBlob() Digest != ToBeSigned for EdDSA")
+	}
+
+	// attach signature
+	ctx := context.Background()
+	sig := signDigest(t, cose.AlgorithmEd25519, key, roundTripSigningRequest(t, req).Digest)
+	envelope, err := s.Attach(ctx, req, sig, notation.SignOptions{})
+	if err != nil {
+		t.Fatalf("Attach() error = %v", err)
+	}
+	v := NewVerifier()
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	v.VerifyOptions.Roots = roots
+	if err := v.VerifyBlob(ctx, envelope, content, notation.VerifyOptions{}); err != nil {
+		t.Fatalf("VerifyBlob() error = %v", err)
+	}
+}
+
+// roundTripSigningRequest encodes and decodes the signing request in JSON as
+// it is passed between the phases.
+func roundTripSigningRequest(t *testing.T, req *SigningRequest) *SigningRequest {
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var decoded SigningRequest
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	return &decoded
+}
+
+// signDigest signs the digest with the key as an offline signing ceremony.
+func signDigest(t *testing.T, alg cose.Algorithm, key crypto.Signer, digest []byte) []byte {
+	signer, err := cose.NewSigner(alg, key)
+	if err != nil {
+		t.Fatalf("cose.NewSigner() error = %v", err)
+	}
+	sig, err := signer.Sign(rand.Reader, digest)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	return sig
+}
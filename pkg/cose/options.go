@@ -0,0 +1,98 @@
+package cose
+
+import (
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/notaryproject/notation-go"
+)
+
+// MediaTypeEnvelope is the media type of the COSE signature envelopes.
+const MediaTypeEnvelope = "application/cose"
+
+// VerifyOptions contains the options of a single verification. The options
+// apply to that call only so that a Verifier can be shared by the calls for
+// different artifacts.
+type VerifyOptions struct {
+	notation.VerifyOptions
+
+	// SignatureMediaType is the media type of the signature envelope, such as
+	// the media type of the signature manifest in the registry. It must be
+	// MediaTypeEnvelope if present.
+	SignatureMediaType string
+
+	// ExpectedDescriptor is the descriptor of the artifact to be verified if
+	// present. The verified descriptor must match its media type, digest, and
+	// size, where they are present.
+	ExpectedDescriptor *notation.Descriptor
+
+	// Roots overrides the trusted roots in `Verifier.VerifyOptions` if
+	// present.
+	Roots *x509.CertPool
+
+	// CurrentTime overrides the time at which the certificates, the signing
+	// time, and the expiry are verified if not zero.
+	CurrentTime time.Time
+
+	// ArtifactReference is the reference of the artifact being verified in
+	// the format of `<registry>/<repository>[:<tag>|@<digest>]`, by which the
+	// trust policy of the verifier is selected. It is required if the
+	// verifier has a trust policy.
+	ArtifactReference string
+}
+
+// Validate validates the verify options.
+func (opts VerifyOptions) Validate() error {
+	if err := opts.VerifyOptions.Validate(); err != nil {
+		return err
+	}
+	mediaType := opts.SignatureMediaType
+	if mediaType != "" && mediaType != MediaTypeEnvelope {
+		return fmt.Errorf("%w: unsupported signature media type: %s",
+			ErrInvalidEnvelope, mediaType)
+	}
+	return nil
+}
+
+// matchDescriptor matches the verified descriptor against the expected
+// descriptor if present.
+func (opts VerifyOptions) matchDescriptor(desc notation.Descriptor) error {
+	expected := opts.ExpectedDescriptor
+	if expected == nil {
+		return nil
+	}
+	if expected.MediaType != "" && desc.MediaType != expected.MediaType {
+		return fmt.Errorf("%w: media type %s, want %s",
+			ErrDescriptorMismatch, desc.MediaType, expected.MediaType)
+	}
+	if expected.Digest != "" && desc.Digest != expected.Digest {
+		return fmt.Errorf("%w: digest %s, want %s",
+			ErrDescriptorMismatch, desc.Digest, expected.Digest)
+	}
+	if expected.Size != 0 && desc.Size != expected.Size {
+		return fmt.Errorf("%w: size %d, want %d",
+			ErrDescriptorMismatch, desc.Size, expected.Size)
+	}
+	return nil
+}
+
+// withOptions returns the verifier with the trusted roots, the current time,
+// and the artifact reference overridden by the verify options. The verifier
+// itself is not modified.
+func (v *Verifier) withOptions(opts VerifyOptions) *Verifier {
+	if opts.Roots == nil && opts.CurrentTime.IsZero() &&
+		opts.ArtifactReference == "" {
+		return v
+	}
+	verifier := *v
+	if opts.Roots != nil {
+		verifier.VerifyOptions.Roots = opts.Roots
+	}
+	if !opts.CurrentTime.IsZero() {
+		verifier.VerifyOptions.CurrentTime = opts.CurrentTime
+		verifier.currentTime = opts.CurrentTime
+	}
+	verifier.artifactReference = opts.ArtifactReference
+	return &verifier
+}
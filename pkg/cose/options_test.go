@@ -0,0 +1,178 @@
+package cose
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/notaryproject/notation-go"
+)
+
+func TestVerifyWithOptions(t *testing.T) {
+	// sign content
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	signer, cert := newTestSigner(t)
+	sig, err := signer.Sign(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	// the shared verifier trusts no roots
+	v := NewVerifier()
+	v.VerifyOptions.Roots = x509.NewCertPool()
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); !errors.Is(err, ErrUntrustedSigner) {
+		t.Fatalf("Verify() error = %v, want %v", err, ErrUntrustedSigner)
+	}
+
+	// per-call roots and expected descriptor
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	opts := VerifyOptions{
+		SignatureMediaType: MediaTypeEnvelope,
+		ExpectedDescriptor: &notation.Descriptor{
+			MediaType: desc.MediaType,
+			Digest:    desc.Digest,
+			Size:      desc.Size,
+		},
+		Roots: roots,
+	}
+	result, err := v.VerifyWithOptions(ctx, sig, opts)
+	if err != nil {
+		t.Fatalf("VerifyWithOptions() error = %v", err)
+	}
+	if !result.Descriptor.Equal(desc) {
+		t.Errorf("VerifyWithOptions() Descriptor = %v, want %v", result.Descriptor, desc)
+	}
+	if v.VerifyOptions.Roots == roots {
+		t.Error("VerifyWithOptions() modified the verifier")
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(opts *VerifyOptions)
+		wantErr error
+	}{
+		{
+			name: "signature media type",
+			modify: func(opts *VerifyOptions) {
+				opts.SignatureMediaType = "application/jose+json"
+			},
+			wantErr: ErrInvalidEnvelope,
+		},
+		{
+			name: "descriptor digest",
+			modify: func(opts *VerifyOptions) {
+				opts.ExpectedDescriptor = &notation.Descriptor{
+					Digest: "sha256:0000000000000000000000000000000000000000000000000000000000000000",
+				}
+			},
+			wantErr: ErrDescriptorMismatch,
+		},
+		{
+			name: "descriptor size",
+			modify: func(opts *VerifyOptions) {
+				opts.ExpectedDescriptor = &notation.Descriptor{
+					Size: desc.Size + 1,
+				}
+			},
+			wantErr: ErrDescriptorMismatch,
+		},
+		{
+			name: "descriptor media type",
+			modify: func(opts *VerifyOptions) {
+				opts.ExpectedDescriptor = &notation.Descriptor{
+					MediaType: "application/octet-stream",
+				}
+			},
+			wantErr: ErrDescriptorMismatch,
+		},
+		{
+			name: "current time after expiry",
+			modify: func(opts *VerifyOptions) {
+				opts.CurrentTime = sOpts.Expiry.Add(time.Second)
+			},
+			wantErr: ErrSignatureExpired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := VerifyOptions{
+				Roots: roots,
+			}
+			tt.modify(&opts)
+			if _, err := v.VerifyWithOptions(ctx, sig, opts); !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyWithOptions() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyBlobWithOptions(t *testing.T) {
+	ctx := context.Background()
+	_, sOpts := generateSigningContent(nil)
+	signer, cert := newTestSigner(t)
+	content := []byte("hello world")
+	sig, err := signer.SignBlob(ctx, content, sOpts)
+	if err != nil {
+		t.Fatalf("SignBlob() error = %v", err)
+	}
+
+	// the shared verifier trusts no roots
+	v := NewVerifier()
+	v.VerifyOptions.Roots = x509.NewCertPool()
+	if err := v.VerifyBlob(ctx, sig, content, notation.VerifyOptions{}); !errors.Is(err, ErrUntrustedSigner) {
+		t.Fatalf("VerifyBlob() error = %v, want %v", err, ErrUntrustedSigner)
+	}
+
+	// per-call roots
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	opts := VerifyOptions{
+		SignatureMediaType: MediaTypeEnvelope,
+		Roots:              roots,
+	}
+	if err := v.VerifyBlobWithOptions(ctx, sig, content, opts); err != nil {
+		t.Fatalf("VerifyBlobWithOptions() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(opts *VerifyOptions)
+		wantErr error
+	}{
+		{
+			name: "signature media type",
+			modify: func(opts *VerifyOptions) {
+				opts.SignatureMediaType = "application/jose+json"
+			},
+			wantErr: ErrInvalidEnvelope,
+		},
+		{
+			name: "current time after expiry",
+			modify: func(opts *VerifyOptions) {
+				opts.CurrentTime = sOpts.Expiry.Add(time.Second)
+			},
+			wantErr: ErrSignatureExpired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := VerifyOptions{
+				Roots: roots,
+			}
+			tt.modify(&opts)
+			if err := v.VerifyBlobWithOptions(ctx, sig, content, opts); !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyBlobWithOptions() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// expected descriptor does not apply to blobs
+	opts.ExpectedDescriptor = &notation.Descriptor{}
+	if err := v.VerifyBlobWithOptions(ctx, sig, content, opts); err == nil {
+		t.Error("VerifyBlobWithOptions() error = nil, want error for expected descriptor")
+	}
+}
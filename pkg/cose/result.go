@@ -0,0 +1,177 @@
+package cose
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/microsoft/notation-cose/pkg/trustpolicy"
+	"github.com/notaryproject/notation-go"
+	"github.com/veraison/go-cose"
+)
+
+// VerificationResult contains the verified descriptor and the metadata of a
+// verified signature.
+type VerificationResult struct {
+	// Descriptor is the verified descriptor of the signed artifact.
+	Descriptor notation.Descriptor
+
+	// Algorithm is the signing algorithm read from the protected header.
+	Algorithm cose.Algorithm
+
+	// CertificateChain is the verified certificate chain of the signer from
+	// the signing certificate to the trusted root.
+	// If the authenticity failure is logged by the trust policy, it is the
+	// unverified certificate chain in the signature.
+	CertificateChain []*x509.Certificate
+
+	// SigningTime is the time claimed by the signer when signing.
+	SigningTime time.Time
+
+	// Expiry is the expiration time of the signature.
+	// A zero value indicates that the signature never expires.
+	Expiry time.Time
+
+	// TimestampTime is the time stamped by the TSA.
+	// A zero value indicates that the timestamp is not verified.
+	TimestampTime time.Time
+
+	// TimestampAuthority is the signing certificate of the TSA if the
+	// timestamp is verified.
+	TimestampAuthority *x509.Certificate
+
+	// UnprotectedHeaders contains the parameters in the unprotected header,
+	// which are not covered by the signature.
+	UnprotectedHeaders cose.UnprotectedHeader
+
+	// TrustPolicy is the trust policy applied to the verification if present.
+	TrustPolicy *trustpolicy.Policy
+
+	// TrustStore is the name of the trust store satisfied by the signature
+	// when verified against the threshold policy.
+	TrustStore string
+
+	// Countersignatures contains the results of the verified
+	// countersignatures if the countersignature policy is present.
+	Countersignatures []*VerificationResult
+
+	// Warnings contains the failures logged rather than enforced by the
+	// verification level of the trust policy.
+	Warnings []error
+
+	// enforceAuthenticity enforces the authenticity checks regardless of the
+	// verification level, so that only signers chaining to the trust store
+	// count toward the threshold policy.
+	enforceAuthenticity bool
+}
+
+// Signer returns the signing certificate.
+func (r *VerificationResult) Signer() *x509.Certificate {
+	if len(r.CertificateChain) == 0 {
+		return nil
+	}
+	return r.CertificateChain[0]
+}
+
+// enforce returns the failure of the check if it is enforced by the
+// verification level of the trust policy. Otherwise, the failure is logged in
+// the warnings.
+func (r *VerificationResult) enforce(check trustpolicy.Check, err error) error {
+	if r.TrustPolicy == nil || r.TrustPolicy.SignatureVerification.Enforces(check) {
+		return err
+	}
+	if r.enforceAuthenticity && (check == trustpolicy.CheckAuthenticity || check == trustpolicy.CheckAuthenticTimestamp) {
+		return err
+	}
+	r.Warnings = append(r.Warnings, err)
+	return nil
+}
+
+// MarshalJSON encodes the verification result in JSON for logging and
+// auditing.
+func (r *VerificationResult) MarshalJSON() ([]byte, error) {
+	type certificate struct {
+		Subject      string    `json:"subject"`
+		Issuer       string    `json:"issuer"`
+		SerialNumber string    `json:"serialNumber"`
+		NotBefore    time.Time `json:"notBefore"`
+		NotAfter     time.Time `json:"notAfter"`
+	}
+	type timestamp struct {
+		Time      time.Time    `json:"time"`
+		Authority *certificate `json:"authority,omitempty"`
+	}
+	type result struct {
+		Descriptor         notation.Descriptor    `json:"descriptor"`
+		Algorithm          string                 `json:"algorithm"`
+		Signer             *certificate           `json:"signer,omitempty"`
+		CertificateChain   [][]byte               `json:"certificateChain"`
+		SigningTime        time.Time              `json:"signingTime"`
+		Expiry             *time.Time             `json:"expiry,omitempty"`
+		Timestamp          *timestamp             `json:"timestamp,omitempty"`
+		UnprotectedHeaders map[string]interface{} `json:"unprotectedHeaders,omitempty"`
+		TrustPolicy        string                 `json:"trustPolicy,omitempty"`
+		TrustStore         string                 `json:"trustStore,omitempty"`
+		Countersignatures  []*VerificationResult  `json:"countersignatures,omitempty"`
+		Warnings           []string               `json:"warnings,omitempty"`
+	}
+	newCertificate := func(cert *x509.Certificate) *certificate {
+		if cert == nil {
+			return nil
+		}
+		return &certificate{
+			Subject:      cert.Subject.String(),
+			Issuer:       cert.Issuer.String(),
+			SerialNumber: cert.SerialNumber.String(),
+			NotBefore:    cert.NotBefore,
+			NotAfter:     cert.NotAfter,
+		}
+	}
+
+	out := result{
+		Descriptor:         r.Descriptor,
+		Algorithm:          r.Algorithm.String(),
+		Signer:             newCertificate(r.Signer()),
+		SigningTime:        r.SigningTime,
+		UnprotectedHeaders: jsonHeaderMap(r.UnprotectedHeaders),
+		TrustStore:         r.TrustStore,
+		Countersignatures:  r.Countersignatures,
+	}
+	for _, cert := range r.CertificateChain {
+		out.CertificateChain = append(out.CertificateChain, cert.Raw)
+	}
+	if r.TrustPolicy != nil {
+		out.TrustPolicy = r.TrustPolicy.Name
+	}
+	for _, warning := range r.Warnings {
+		out.Warnings = append(out.Warnings, warning.Error())
+	}
+	if !r.Expiry.IsZero() {
+		out.Expiry = &r.Expiry
+	}
+	if !r.TimestampTime.IsZero() {
+		out.Timestamp = &timestamp{
+			Time:      r.TimestampTime,
+			Authority: newCertificate(r.TimestampAuthority),
+		}
+	}
+	return json.Marshal(out)
+}
+
+// jsonHeaderMap converts a COSE header map to a JSON compatible map by
+// stringifying the header labels.
+func jsonHeaderMap(header map[interface{}]interface{}) map[string]interface{} {
+	if len(header) == 0 {
+		return nil
+	}
+	m := make(map[string]interface{}, len(header))
+	for label, value := range header {
+		if value, ok := value.(map[interface{}]interface{}); ok {
+			m[fmt.Sprint(label)] = jsonHeaderMap(value)
+			continue
+		}
+		m[fmt.Sprint(label)] = value
+	}
+	return m
+}
@@ -0,0 +1,498 @@
+package cose
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// maxRevocationResponseSize specifies the max size of the CRL or the OCSP
+// response fetched by the HTTPFetcher.
+const maxRevocationResponseSize = 32 * 1024 * 1024
+
+// defaultRevocationCacheDuration specifies how long the revocation data is
+// cached if the data does not indicate its next update.
+const defaultRevocationCacheDuration = time.Hour
+
+// RevocationMode specifies how the verifier reacts if the revocation status of
+// a certificate cannot be determined.
+type RevocationMode int
+
+// Revocation modes.
+const (
+	// RevocationModeHardFail fails the verification if the revocation status
+	// of a certificate with revocation endpoints cannot be determined.
+	RevocationModeHardFail RevocationMode = iota
+
+	// RevocationModeSoftFail tolerates unreachable or invalid revocation data.
+	// Revoked certificates still fail the verification.
+	RevocationModeSoftFail
+)
+
+// Fetcher fetches revocation data from CRL distribution points and OCSP
+// responders.
+type Fetcher interface {
+	// FetchCRL fetches the DER encoded CRL from the distribution point.
+	FetchCRL(ctx context.Context, url string) ([]byte, error)
+
+	// FetchOCSP sends the DER encoded OCSP request to the responder and
+	// returns the DER encoded OCSP response.
+	FetchOCSP(ctx context.Context, url string, req []byte) ([]byte, error)
+}
+
+// HTTPFetcher fetches revocation data over HTTP.
+type HTTPFetcher struct {
+	// Client is the HTTP client for fetching revocation data.
+	// If not present, http.DefaultClient is used.
+	Client *http.Client
+}
+
+// FetchCRL fetches the DER encoded CRL from the distribution point.
+func (f *HTTPFetcher) FetchCRL(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return f.do(req)
+}
+
+// FetchOCSP sends the DER encoded OCSP request to the responder and returns
+// the DER encoded OCSP response.
+// Reference: RFC 6960 A.1 Request.
+func (f *HTTPFetcher) FetchOCSP(ctx context.Context, url string, ocspReq []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(ocspReq))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	return f.do(req)
+}
+
+// do sends the HTTP request and returns the response body.
+func (f *HTTPFetcher) do(req *http.Request) ([]byte, error) {
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %q: unexpected status: %s", req.Method, req.URL, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRevocationResponseSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxRevocationResponseSize {
+		return nil, fmt.Errorf("%s %q: response exceeds %d bytes", req.Method, req.URL, maxRevocationResponseSize)
+	}
+	return body, nil
+}
+
+// RevocationChecker checks the revocation status of certificate chains via
+// OCSP and CRL. OCSP responders are preferred over CRL distribution points.
+// Fetched revocation data is cached until its next update.
+type RevocationChecker struct {
+	// Fetcher fetches revocation data.
+	// If not present, a HTTPFetcher with http.DefaultClient is used.
+	Fetcher Fetcher
+
+	// Mode specifies how to react if the revocation status cannot be
+	// determined.
+	Mode RevocationMode
+
+	// CacheDuration specifies how long the revocation data is cached if the
+	// data does not indicate its next update.
+	// If not present, the revocation data is cached for an hour.
+	CacheDuration time.Duration
+
+	cacheLock sync.Mutex
+	cache     map[string]revocationCacheEntry
+}
+
+// revocationCacheEntry is a cached revocation status.
+type revocationCacheEntry struct {
+	// crl is the parsed CRL if the entry is a CRL.
+	crl *pkix.CertificateList
+
+	// ocsp is the parsed OCSP response if the entry is an OCSP response.
+	ocsp *ocsp.Response
+
+	// expiresAt is the time when the entry is evicted.
+	expiresAt time.Time
+}
+
+// NewRevocationChecker creates a revocation checker with the fetcher and the
+// mode.
+func NewRevocationChecker(fetcher Fetcher, mode RevocationMode) *RevocationChecker {
+	return &RevocationChecker{
+		Fetcher: fetcher,
+		Mode:    mode,
+	}
+}
+
+// Check checks the revocation status of each certificate in the verified
+// certificate chain against its issuer. The chain is ordered from the leaf
+// certificate to the root certificate, and the root certificate is not
+// checked.
+func (c *RevocationChecker) Check(ctx context.Context, chain []*x509.Certificate) error {
+	return c.CheckStapled(ctx, chain, nil, time.Time{})
+}
+
+// CheckStapled checks the revocation status of each certificate in the
+// verified certificate chain like Check, but prefers the stapled revocation
+// data over fetching. The stapled revocation data is only used if it is valid
+// at the reference time, which is the verified timestamp or the current time,
+// and the revocation status is fetched otherwise.
+func (c *RevocationChecker) CheckStapled(ctx context.Context, chain []*x509.Certificate, stapled *RevocationData, at time.Time) error {
+	for i := 0; i < len(chain)-1; i++ {
+		if err := c.checkCertificate(ctx, chain[i], chain[i+1], stapled, at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckAt checks the revocation status of each certificate in the verified
+// certificate chain as of the reference time like CheckStapled, for verifying
+// at a historical time. Certificates revoked after the reference time are
+// considered not revoked at that time.
+func (c *RevocationChecker) CheckAt(ctx context.Context, chain []*x509.Certificate, stapled *RevocationData, at time.Time) error {
+	for i := 0; i < len(chain)-1; i++ {
+		err := c.checkCertificate(ctx, chain[i], chain[i+1], stapled, at)
+		var revoked *RevokedError
+		if errors.As(err, &revoked) && revoked.RevocationTime.After(at) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCertificate checks the revocation status of the certificate issued by
+// the issuer.
+func (c *RevocationChecker) checkCertificate(ctx context.Context, cert, issuer *x509.Certificate, stapled *RevocationData, at time.Time) error {
+	if len(cert.OCSPServer) == 0 && len(cert.CRLDistributionPoints) == 0 {
+		// no revocation information available
+		return nil
+	}
+
+	// check stapled revocation data first.
+	if revoked, ok := stapled.check(cert, issuer, at); ok {
+		return revoked
+	}
+
+	// check OCSP responders, falling back to CRL distribution points.
+	var errs []error
+	for _, server := range cert.OCSPServer {
+		revoked, err := c.checkOCSP(ctx, server, cert, issuer)
+		if err == nil {
+			return revoked
+		}
+		errs = append(errs, err)
+	}
+	for _, url := range cert.CRLDistributionPoints {
+		revoked, err := c.checkCRL(ctx, url, cert, issuer)
+		if err == nil {
+			return revoked
+		}
+		errs = append(errs, err)
+	}
+
+	if c.Mode == RevocationModeSoftFail {
+		return nil
+	}
+	return &RevocationCheckError{
+		Certificate: cert,
+		Errs:        errs,
+	}
+}
+
+// checkOCSP checks the revocation status of the certificate using the OCSP
+// responder. The returned error indicates that the status cannot be determined
+// while the returned revoked error indicates the certificate is revoked.
+func (c *RevocationChecker) checkOCSP(ctx context.Context, server string, cert, issuer *x509.Certificate) (revoked error, err error) {
+	key := "ocsp:" + server + "#" + string(issuer.RawSubjectPublicKeyInfo) + "#" + cert.SerialNumber.String()
+	entry, ok := c.loadCache(key)
+	if !ok {
+		resp, err := fetchOCSP(ctx, c.fetcher(), server, cert, issuer)
+		if err != nil {
+			return nil, err
+		}
+		entry = revocationCacheEntry{
+			ocsp:      resp,
+			expiresAt: c.cacheExpiry(resp.NextUpdate),
+		}
+		c.storeCache(key, entry)
+	}
+
+	switch entry.ocsp.Status {
+	case ocsp.Good:
+		return nil, nil
+	case ocsp.Revoked:
+		return &RevokedError{
+			Certificate:    cert,
+			RevocationTime: entry.ocsp.RevokedAt,
+			Reason:         entry.ocsp.RevocationReason,
+		}, nil
+	}
+	return nil, fmt.Errorf("ocsp %q: unknown certificate status", server)
+}
+
+// checkCRL checks the revocation status of the certificate using the CRL
+// distribution point. The returned error indicates that the status cannot be
+// determined while the returned revoked error indicates the certificate is
+// revoked.
+func (c *RevocationChecker) checkCRL(ctx context.Context, url string, cert, issuer *x509.Certificate) (revoked error, err error) {
+	key := "crl:" + url + "#" + string(issuer.RawSubjectPublicKeyInfo)
+	entry, ok := c.loadCache(key)
+	if !ok {
+		_, crl, err := fetchCRL(ctx, c.fetcher(), url, issuer)
+		if err != nil {
+			return nil, err
+		}
+		entry = revocationCacheEntry{
+			crl:       crl,
+			expiresAt: c.cacheExpiry(crl.TBSCertList.NextUpdate),
+		}
+		c.storeCache(key, entry)
+	}
+	return checkCRL(entry.crl, cert), nil
+}
+
+// fetcher returns the configured fetcher or the default HTTP fetcher.
+func (c *RevocationChecker) fetcher() Fetcher {
+	if c.Fetcher == nil {
+		return &HTTPFetcher{}
+	}
+	return c.Fetcher
+}
+
+// cacheExpiry returns the time when the revocation data with the next update
+// is evicted from the cache.
+func (c *RevocationChecker) cacheExpiry(nextUpdate time.Time) time.Time {
+	if !nextUpdate.IsZero() {
+		return nextUpdate
+	}
+	duration := c.CacheDuration
+	if duration == 0 {
+		duration = defaultRevocationCacheDuration
+	}
+	return time.Now().Add(duration)
+}
+
+// loadCache loads the unexpired cache entry.
+func (c *RevocationChecker) loadCache(key string) (revocationCacheEntry, bool) {
+	c.cacheLock.Lock()
+	defer c.cacheLock.Unlock()
+	entry, ok := c.cache[key]
+	if !ok || !time.Now().Before(entry.expiresAt) {
+		return revocationCacheEntry{}, false
+	}
+	return entry, true
+}
+
+// storeCache stores the entry to the cache.
+func (c *RevocationChecker) storeCache(key string, entry revocationCacheEntry) {
+	c.cacheLock.Lock()
+	defer c.cacheLock.Unlock()
+	if c.cache == nil {
+		c.cache = make(map[string]revocationCacheEntry)
+	}
+	c.cache[key] = entry
+}
+
+// fetchOCSP fetches the OCSP response of the certificate from the OCSP
+// responder, and verifies that the response is signed for the issuer and is
+// not expired.
+func fetchOCSP(ctx context.Context, fetcher Fetcher, server string, cert, issuer *x509.Certificate) (*ocsp.Response, error) {
+	req, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return nil, err
+	}
+	respBytes, err := fetcher.FetchOCSP(ctx, server, req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ocsp.ParseResponseForCert(respBytes, cert, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("ocsp %q: %w", server, err)
+	}
+	if !resp.NextUpdate.IsZero() && time.Now().After(resp.NextUpdate) {
+		return nil, fmt.Errorf("ocsp %q: response expired at %v", server, resp.NextUpdate)
+	}
+	return resp, nil
+}
+
+// fetchCRL fetches the CRL from the distribution point, and verifies that the
+// CRL is signed by the issuer and is not expired.
+func fetchCRL(ctx context.Context, fetcher Fetcher, url string, issuer *x509.Certificate) ([]byte, *pkix.CertificateList, error) {
+	crlBytes, err := fetcher.FetchCRL(ctx, url)
+	if err != nil {
+		return nil, nil, err
+	}
+	crl, err := x509.ParseCRL(crlBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("crl %q: %w", url, err)
+	}
+	if err := issuer.CheckCRLSignature(crl); err != nil {
+		return nil, nil, fmt.Errorf("crl %q: %w", url, err)
+	}
+	nextUpdate := crl.TBSCertList.NextUpdate
+	if !nextUpdate.IsZero() && time.Now().After(nextUpdate) {
+		return nil, nil, fmt.Errorf("crl %q: expired at %v", url, nextUpdate)
+	}
+	return crlBytes, crl, nil
+}
+
+// checkCRL returns a revoked error if the certificate is listed in the CRL.
+func checkCRL(crl *pkix.CertificateList, cert *x509.Certificate) error {
+	for _, revokedCert := range crl.TBSCertList.RevokedCertificates {
+		if revokedCert.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			return &RevokedError{
+				Certificate:    cert,
+				RevocationTime: revokedCert.RevocationTime,
+			}
+		}
+	}
+	return nil
+}
+
+// RevocationData contains the DER encoded OCSP responses and CRLs of a
+// certificate chain, which are stapled in the signature for verification in
+// the offline environments.
+type RevocationData struct {
+	// OCSPResponses contains the DER encoded OCSP responses.
+	OCSPResponses [][]byte
+
+	// CRLs contains the DER encoded CRLs.
+	CRLs [][]byte
+}
+
+// FetchRevocationData fetches the revocation data of each certificate in the
+// certificate chain ordered from the leaf certificate. The last certificate
+// is not checked since its issuer is unknown.
+// An error is returned if a certificate is revoked or its revocation status
+// cannot be fetched.
+func FetchRevocationData(ctx context.Context, fetcher Fetcher, chain []*x509.Certificate) (*RevocationData, error) {
+	if fetcher == nil {
+		fetcher = &HTTPFetcher{}
+	}
+	data := &RevocationData{}
+	for i := 0; i < len(chain)-1; i++ {
+		if err := data.fetch(ctx, fetcher, chain[i], chain[i+1]); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// fetch fetches the revocation data of the certificate issued by the issuer.
+// OCSP responses are preferred over CRLs.
+func (d *RevocationData) fetch(ctx context.Context, fetcher Fetcher, cert, issuer *x509.Certificate) error {
+	if len(cert.OCSPServer) == 0 && len(cert.CRLDistributionPoints) == 0 {
+		// no revocation information available
+		return nil
+	}
+
+	var errs []error
+	for _, server := range cert.OCSPServer {
+		resp, err := fetchOCSP(ctx, fetcher, server, cert, issuer)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if resp.Status == ocsp.Revoked {
+			return &RevokedError{
+				Certificate:    cert,
+				RevocationTime: resp.RevokedAt,
+				Reason:         resp.RevocationReason,
+			}
+		}
+		if resp.Status == ocsp.Good {
+			d.OCSPResponses = append(d.OCSPResponses, resp.Raw)
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("ocsp %q: unknown certificate status", server))
+	}
+	for _, url := range cert.CRLDistributionPoints {
+		crlBytes, crl, err := fetchCRL(ctx, fetcher, url, issuer)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if revoked := checkCRL(crl, cert); revoked != nil {
+			return revoked
+		}
+		d.CRLs = append(d.CRLs, crlBytes)
+		return nil
+	}
+	return &RevocationCheckError{
+		Certificate: cert,
+		Errs:        errs,
+	}
+}
+
+// check checks the revocation status of the certificate against the stapled
+// revocation data valid at the reference time. It returns false if no
+// applicable revocation data is found.
+func (d *RevocationData) check(cert, issuer *x509.Certificate, at time.Time) (revoked error, ok bool) {
+	if d == nil {
+		return nil, false
+	}
+	for _, respBytes := range d.OCSPResponses {
+		resp, err := ocsp.ParseResponseForCert(respBytes, cert, issuer)
+		if err != nil || !isFresh(resp.ThisUpdate, resp.NextUpdate, at) {
+			continue
+		}
+		switch resp.Status {
+		case ocsp.Good:
+			return nil, true
+		case ocsp.Revoked:
+			return &RevokedError{
+				Certificate:    cert,
+				RevocationTime: resp.RevokedAt,
+				Reason:         resp.RevocationReason,
+			}, true
+		}
+	}
+	for _, crlBytes := range d.CRLs {
+		crl, err := x509.ParseCRL(crlBytes)
+		if err != nil || issuer.CheckCRLSignature(crl) != nil {
+			continue
+		}
+		if !isFresh(crl.TBSCertList.ThisUpdate, crl.TBSCertList.NextUpdate, at) {
+			continue
+		}
+		return checkCRL(crl, cert), true
+	}
+	return nil, false
+}
+
+// isFresh checks if the revocation data issued at thisUpdate and expiring at
+// nextUpdate is valid at the reference time. Revocation data without
+// nextUpdate is never fresh since newer revocation information may be
+// available at any time.
+func isFresh(thisUpdate, nextUpdate, at time.Time) bool {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	if nextUpdate.IsZero() || thisUpdate.After(at) {
+		return false
+	}
+	return !at.After(nextUpdate)
+}
@@ -0,0 +1,577 @@
+package cose
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/notaryproject/notation-go"
+	"github.com/notaryproject/notation-go/crypto/timestamp/timestamptest"
+	"github.com/veraison/go-cose"
+	"golang.org/x/crypto/ocsp"
+)
+
+func TestRevocationCheckOCSP(t *testing.T) {
+	ca, err := newTestCA()
+	if err != nil {
+		t.Fatalf("newTestCA() error = %v", err)
+	}
+	server := ca.startServer()
+	defer server.Close()
+	key, cert, err := ca.issue(server.URL+"/ocsp", "")
+	if err != nil {
+		t.Fatalf("testCA.issue() error = %v", err)
+	}
+	sig := signWithChain(t, key, cert, ca.cert)
+
+	// verify good certificate
+	v := ca.verifier()
+	v.Revocation = NewRevocationChecker(&HTTPFetcher{Client: server.Client()}, RevocationModeHardFail)
+	ctx := context.Background()
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	// verify revoked certificate with a fresh cache
+	ca.revoke(cert)
+	v.Revocation = NewRevocationChecker(&HTTPFetcher{Client: server.Client()}, RevocationModeHardFail)
+	_, err = v.Verify(ctx, sig, notation.VerifyOptions{})
+	if !errors.Is(err, ErrCertificateRevoked) {
+		t.Fatalf("Verify() error = %v, want %v", err, ErrCertificateRevoked)
+	}
+	if !errors.Is(err, ErrUntrustedSigner) {
+		t.Errorf("Verify() error = %v, want %v", err, ErrUntrustedSigner)
+	}
+}
+
+func TestRevocationCheckCRL(t *testing.T) {
+	ca, err := newTestCA()
+	if err != nil {
+		t.Fatalf("newTestCA() error = %v", err)
+	}
+	server := ca.startServer()
+	defer server.Close()
+	key, cert, err := ca.issue("", server.URL+"/crl")
+	if err != nil {
+		t.Fatalf("testCA.issue() error = %v", err)
+	}
+	sig := signWithChain(t, key, cert, ca.cert)
+
+	// verify good certificate
+	v := ca.verifier()
+	v.Revocation = NewRevocationChecker(&HTTPFetcher{Client: server.Client()}, RevocationModeHardFail)
+	ctx := context.Background()
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	// the CRL is cached
+	ca.revoke(cert)
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if got := ca.requests("/crl"); got != 1 {
+		t.Errorf("CRL requests = %d, want 1", got)
+	}
+
+	// verify revoked certificate with a fresh cache
+	v.Revocation = NewRevocationChecker(&HTTPFetcher{Client: server.Client()}, RevocationModeHardFail)
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); !errors.Is(err, ErrCertificateRevoked) {
+		t.Fatalf("Verify() error = %v, want %v", err, ErrCertificateRevoked)
+	}
+}
+
+func TestRevocationCheckUnavailable(t *testing.T) {
+	ca, err := newTestCA()
+	if err != nil {
+		t.Fatalf("newTestCA() error = %v", err)
+	}
+	server := ca.startServer()
+	defer server.Close()
+	key, cert, err := ca.issue(server.URL+"/unavailable", server.URL+"/unavailable")
+	if err != nil {
+		t.Fatalf("testCA.issue() error = %v", err)
+	}
+	sig := signWithChain(t, key, cert, ca.cert)
+
+	// should fail in the hard-fail mode
+	v := ca.verifier()
+	v.Revocation = NewRevocationChecker(&HTTPFetcher{Client: server.Client()}, RevocationModeHardFail)
+	ctx := context.Background()
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); !errors.Is(err, ErrRevocationUnknown) {
+		t.Fatalf("Verify() error = %v, want %v", err, ErrRevocationUnknown)
+	}
+
+	// should pass in the soft-fail mode
+	v.Revocation = NewRevocationChecker(&HTTPFetcher{Client: server.Client()}, RevocationModeSoftFail)
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+}
+
+func TestRevocationStapled(t *testing.T) {
+	for _, endpoint := range []string{"/ocsp", "/crl"} {
+		t.Run(endpoint, func(t *testing.T) {
+			ca, err := newTestCA()
+			if err != nil {
+				t.Fatalf("newTestCA() error = %v", err)
+			}
+			server := ca.startServer()
+			defer server.Close()
+			var key *rsa.PrivateKey
+			var cert *x509.Certificate
+			if endpoint == "/ocsp" {
+				key, cert, err = ca.issue(server.URL+endpoint, "")
+			} else {
+				key, cert, err = ca.issue("", server.URL+endpoint)
+			}
+			if err != nil {
+				t.Fatalf("testCA.issue() error = %v", err)
+			}
+
+			// sign with revocation data stapled
+			s, err := NewSigner(key, []*x509.Certificate{cert, ca.cert})
+			if err != nil {
+				t.Fatalf("NewSigner() error = %v", err)
+			}
+			s.RevocationFetcher = &HTTPFetcher{Client: server.Client()}
+			ctx := context.Background()
+			desc, sOpts := generateSigningContent(nil)
+			sig, err := s.Sign(ctx, desc, sOpts)
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+
+			// verify offline with the stapled revocation data
+			v := ca.verifier()
+			v.Revocation = NewRevocationChecker(offlineFetcher{}, RevocationModeHardFail)
+			result, err := v.VerifyWithResult(ctx, sig, notation.VerifyOptions{})
+			if err != nil {
+				t.Fatalf("VerifyWithResult() error = %v", err)
+			}
+			if got := ca.requests(endpoint); got != 1 {
+				t.Errorf("requests = %d, want 1", got)
+			}
+
+			// stapled revocation data is not fresh at a later time
+			msg := &cose.Sign1Message{}
+			if err := msg.UnmarshalCBOR(sig); err != nil {
+				t.Fatalf("Sign1Message.UnmarshalCBOR() error = %v", err)
+			}
+			stapled, err := revocationDataFromHeader(msg.Headers.Unprotected)
+			if err != nil {
+				t.Fatalf("revocationDataFromHeader() error = %v", err)
+			}
+			err = v.Revocation.CheckStapled(ctx, result.CertificateChain, stapled, time.Now().Add(2*time.Hour))
+			if !errors.Is(err, ErrRevocationUnknown) {
+				t.Errorf("CheckStapled() error = %v, want %v", err, ErrRevocationUnknown)
+			}
+
+			// should fail to sign with a revoked certificate
+			ca.revoke(cert)
+			if _, err := s.Sign(ctx, desc, sOpts); !errors.Is(err, ErrCertificateRevoked) {
+				t.Errorf("Sign() error = %v, want %v", err, ErrCertificateRevoked)
+			}
+		})
+	}
+}
+
+func TestRevocationStapledBackdated(t *testing.T) {
+	ca, err := newTestCA()
+	if err != nil {
+		t.Fatalf("newTestCA() error = %v", err)
+	}
+	server := ca.startServer()
+	defer server.Close()
+	key, cert, err := ca.issue(server.URL+"/ocsp", "")
+	if err != nil {
+		t.Fatalf("testCA.issue() error = %v", err)
+	}
+
+	// the signing time is set back to the validity of the stapled response
+	s, err := NewSigner(key, []*x509.Certificate{cert, ca.cert})
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	s.RevocationFetcher = &HTTPFetcher{Client: server.Client()}
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	sOpts.Expiry = time.Now().Add(12 * time.Hour)
+	sig, err := s.Sign(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	ca.revoke(cert)
+
+	// the stapled response is not fresh at the time of verification
+	v := ca.verifier()
+	v.Clock = FixedClock(time.Now().Add(2 * time.Hour))
+	v.Revocation = NewRevocationChecker(&HTTPFetcher{Client: server.Client()}, RevocationModeHardFail)
+	if _, err := v.VerifyWithResult(ctx, sig, notation.VerifyOptions{}); !errors.Is(err, ErrCertificateRevoked) {
+		t.Errorf("VerifyWithResult() error = %v, want %v", err, ErrCertificateRevoked)
+	}
+	if got := ca.requests("/ocsp"); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+}
+
+func TestRevocationStapledWithoutNextUpdate(t *testing.T) {
+	ca, err := newTestCA()
+	if err != nil {
+		t.Fatalf("newTestCA() error = %v", err)
+	}
+	_, cert, err := ca.issue("http://localhost/ocsp", "http://localhost/crl")
+	if err != nil {
+		t.Fatalf("testCA.issue() error = %v", err)
+	}
+	chain := []*x509.Certificate{cert, ca.cert}
+	checker := NewRevocationChecker(offlineFetcher{}, RevocationModeHardFail)
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, nextUpdate := range []time.Time{{}, now.Add(time.Hour)} {
+		ocspResp, err := ocsp.CreateResponse(ca.cert, ca.cert, ocsp.Response{
+			Status:       ocsp.Good,
+			SerialNumber: cert.SerialNumber,
+			ThisUpdate:   now.Add(-time.Hour),
+			NextUpdate:   nextUpdate,
+		}, ca.key)
+		if err != nil {
+			t.Fatalf("ocsp.CreateResponse() error = %v", err)
+		}
+		crl := ca.createCRL(t, now.Add(-time.Hour), nextUpdate)
+
+		for name, stapled := range map[string]*RevocationData{
+			"ocsp": {OCSPResponses: [][]byte{ocspResp}},
+			"crl":  {CRLs: [][]byte{crl}},
+		} {
+			err := checker.CheckStapled(ctx, chain, stapled, now)
+			if nextUpdate.IsZero() {
+				// revocation data without nextUpdate is never fresh
+				if !errors.Is(err, ErrRevocationUnknown) {
+					t.Errorf("CheckStapled() with %s without nextUpdate error = %v, want %v", name, err, ErrRevocationUnknown)
+				}
+			} else if err != nil {
+				t.Errorf("CheckStapled() with %s error = %v", name, err)
+			}
+		}
+	}
+}
+
+// offlineFetcher simulates an air-gapped environment.
+type offlineFetcher struct{}
+
+// FetchCRL always fails.
+func (offlineFetcher) FetchCRL(ctx context.Context, url string) ([]byte, error) {
+	return nil, errors.New("offline")
+}
+
+// FetchOCSP always fails.
+func (offlineFetcher) FetchOCSP(ctx context.Context, url string, req []byte) ([]byte, error) {
+	return nil, errors.New("offline")
+}
+
+func TestVerifyAt(t *testing.T) {
+	ca, err := newTestCA()
+	if err != nil {
+		t.Fatalf("newTestCA() error = %v", err)
+	}
+	server := ca.startServer()
+	defer server.Close()
+	key, cert, err := ca.issue(server.URL+"/ocsp", "")
+	if err != nil {
+		t.Fatalf("testCA.issue() error = %v", err)
+	}
+	tsa, err := timestamptest.NewTSA()
+	if err != nil {
+		t.Fatalf("timestamptest.NewTSA() error = %v", err)
+	}
+	s, err := NewSigner(key, []*x509.Certificate{cert, ca.cert})
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(tsa)
+	sig, err := s.Sign(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	// revoked after the verification time
+	revokedAt := time.Now().Add(30 * time.Minute).Truncate(time.Second)
+	ca.revokeAt(cert, revokedAt)
+	v := ca.verifier()
+	v.TSAVerifyOptions.Roots = sOpts.TSAVerifyOptions.Roots
+	v.Revocation = NewRevocationChecker(&HTTPFetcher{Client: server.Client()}, RevocationModeHardFail)
+	v.VerifyAt = revokedAt.Add(-time.Minute)
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		verifyAt time.Time
+		wantErr  error
+	}{
+		{
+			name:     "revoked",
+			verifyAt: revokedAt.Add(time.Minute),
+			wantErr:  ErrCertificateRevoked,
+		},
+		{
+			name:     "expired certificate with timestamp",
+			verifyAt: cert.NotAfter.Add(time.Minute),
+			wantErr:  ErrUntrustedSigner,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v.VerifyAt = tt.verifyAt
+			if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// expired signature without revocation checking
+	v.Revocation = nil
+	v.VerifyAt = sOpts.Expiry.Add(time.Minute)
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); !errors.Is(err, ErrSignatureExpired) {
+		t.Errorf("Verify() error = %v, want %v", err, ErrSignatureExpired)
+	}
+}
+
+// testCA is a test certificate authority serving OCSP responses and CRLs.
+type testCA struct {
+	key  *rsa.PrivateKey
+	cert *x509.Certificate
+
+	lock    sync.Mutex
+	revoked map[string]time.Time
+	hits    map[string]int
+}
+
+// newTestCA creates a test certificate authority.
+func newTestCA() (*testCA, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			CommonName: "test CA",
+		},
+		NotBefore:             now,
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		return nil, err
+	}
+	return &testCA{
+		key:     key,
+		cert:    cert,
+		revoked: make(map[string]time.Time),
+		hits:    make(map[string]int),
+	}, nil
+}
+
+// issue issues a code signing certificate with the revocation endpoints.
+func (ca *testCA) issue(ocspServer, crlDistributionPoint string) (*rsa.PrivateKey, *x509.Certificate, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+	serialNumber, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			CommonName: "test",
+		},
+		NotBefore:   now,
+		NotAfter:    now.Add(24 * time.Hour),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}
+	if ocspServer != "" {
+		template.OCSPServer = []string{ocspServer}
+	}
+	if crlDistributionPoint != "" {
+		template.CRLDistributionPoints = []string{crlDistributionPoint}
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, cert, nil
+}
+
+// createCRL creates an empty CRL, which omits nextUpdate if it is zero.
+func (ca *testCA) createCRL(t *testing.T, thisUpdate, nextUpdate time.Time) []byte {
+	t.Helper()
+	algorithm := pkix.AlgorithmIdentifier{
+		Algorithm: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}, // sha256WithRSAEncryption
+	}
+	tbs := pkix.TBSCertificateList{
+		Version:    1,
+		Signature:  algorithm,
+		Issuer:     ca.cert.Subject.ToRDNSequence(),
+		ThisUpdate: thisUpdate.UTC(),
+		NextUpdate: nextUpdate.UTC(),
+	}
+	tbsBytes, err := asn1.Marshal(tbs)
+	if err != nil {
+		t.Fatalf("asn1.Marshal() error = %v", err)
+	}
+	digest := sha256.Sum256(tbsBytes)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, ca.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("rsa.SignPKCS1v15() error = %v", err)
+	}
+	crl, err := asn1.Marshal(pkix.CertificateList{
+		TBSCertList:        tbs,
+		SignatureAlgorithm: algorithm,
+		SignatureValue:     asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
+	})
+	if err != nil {
+		t.Fatalf("asn1.Marshal() error = %v", err)
+	}
+	return crl
+}
+
+// revoke revokes the certificate.
+func (ca *testCA) revoke(cert *x509.Certificate) {
+	ca.revokeAt(cert, time.Now().Add(-time.Minute).Truncate(time.Second))
+}
+
+// revokeAt revokes the certificate at the specified time.
+func (ca *testCA) revokeAt(cert *x509.Certificate, revokedAt time.Time) {
+	ca.lock.Lock()
+	defer ca.lock.Unlock()
+	ca.revoked[cert.SerialNumber.String()] = revokedAt
+}
+
+// requests returns the number of requests served at the path.
+func (ca *testCA) requests(path string) int {
+	ca.lock.Lock()
+	defer ca.lock.Unlock()
+	return ca.hits[path]
+}
+
+// verifier returns a verifier trusting the CA.
+func (ca *testCA) verifier() *Verifier {
+	v := NewVerifier()
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	v.VerifyOptions.Roots = roots
+	return v
+}
+
+// startServer starts a HTTP server serving OCSP responses at "/ocsp" and the
+// CRL at "/crl".
+func (ca *testCA) startServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ca.lock.Lock()
+		defer ca.lock.Unlock()
+		ca.hits[r.URL.Path]++
+
+		now := time.Now()
+		switch r.URL.Path {
+		case "/ocsp":
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			req, err := ocsp.ParseRequest(body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			template := ocsp.Response{
+				Status:       ocsp.Good,
+				SerialNumber: req.SerialNumber,
+				ThisUpdate:   now.Add(-time.Hour),
+				NextUpdate:   now.Add(time.Hour),
+			}
+			if revokedAt, ok := ca.revoked[req.SerialNumber.String()]; ok {
+				template.Status = ocsp.Revoked
+				template.RevokedAt = revokedAt
+				template.RevocationReason = ocsp.KeyCompromise
+			}
+			resp, err := ocsp.CreateResponse(ca.cert, ca.cert, template, ca.key)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Write(resp)
+		case "/crl":
+			var revokedCerts []pkix.RevokedCertificate
+			for serial, revokedAt := range ca.revoked {
+				serialNumber, _ := new(big.Int).SetString(serial, 10)
+				revokedCerts = append(revokedCerts, pkix.RevokedCertificate{
+					SerialNumber:   serialNumber,
+					RevocationTime: revokedAt,
+				})
+			}
+			crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+				RevokedCertificates: revokedCerts,
+				Number:              big.NewInt(int64(ca.hits[r.URL.Path])),
+				ThisUpdate:          now.Add(-time.Hour),
+				NextUpdate:          now.Add(time.Hour),
+			}, ca.cert, ca.key)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Write(crl)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+// signWithChain signs test content with the key and the certificate chain.
+func signWithChain(t *testing.T, key *rsa.PrivateKey, certChain ...*x509.Certificate) []byte {
+	t.Helper()
+	s, err := NewSigner(key, certChain)
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	desc, sOpts := generateSigningContent(nil)
+	sig, err := s.Sign(context.Background(), desc, sOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	return sig
+}
@@ -8,7 +8,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"time"
 
 	"github.com/notaryproject/notation-go"
 	"github.com/notaryproject/notation-go/crypto/timestamp"
@@ -16,14 +15,77 @@ import (
 	"github.com/veraison/go-cose"
 )
 
+// MediaTypeBlob is the content type of the detached payload signed by
+// `Signer.SignBlob`.
+const MediaTypeBlob = "application/octet-stream"
+
 // Signer signs artifacts and generates COSE signatures.
 type Signer struct {
-	// base is the base COSE signer
+	// alg is the signing algorithm.
+	alg cose.Algorithm
+
+	// base is the base COSE signer of the in-process key.
 	base cose.Signer
 
+	// external is the external signer if the key is out of the process.
+	external ExternalSigner
+
 	// certChain contains the X.509 public key certificate or certificate chain
 	// corresponding to the key used to generate the signature.
 	certChain [][]byte
+
+	// certs is the parsed certChain.
+	certs []*x509.Certificate
+
+	// RevocationFetcher fetches the OCSP responses or the CRLs of the
+	// certificate chain if present. The fetched revocation data is stapled in
+	// the unprotected header of the signature so that the signature can be
+	// verified in the offline environments.
+	RevocationFetcher Fetcher
+
+	// ProtectedHeaders contains additional parameters of the protected
+	// header. Parameters set by the signer, such as the algorithm and the
+	// certificate chain, cannot be overridden.
+	ProtectedHeaders cose.ProtectedHeader
+
+	// CriticalHeaders lists the labels of the parameters in ProtectedHeaders
+	// to be marked critical, which must be understood by the verifiers.
+	CriticalHeaders []interface{}
+
+	// UnprotectedHeaders contains additional parameters of the unprotected
+	// header. Parameters set by the signer, such as the timestamp, cannot be
+	// overridden.
+	UnprotectedHeaders cose.UnprotectedHeader
+
+	// Timestamping timestamps the signatures with multiple TSAs if present, in
+	// place of the TSA in the signing options. The timestamps are verified
+	// against the `TSAVerifyOptions` in the signing options.
+	Timestamping *TimestampPolicy
+
+	// Clock provides the signing time if present. Otherwise, the system time
+	// is used.
+	Clock Clock
+}
+
+// reservedProtectedHeaders lists the labels of the protected header
+// parameters set by the signer.
+var reservedProtectedHeaders = []interface{}{
+	cose.HeaderLabelAlgorithm,
+	cose.HeaderLabelCritical,
+	cose.HeaderLabelContentType,
+	cose.HeaderLabelX5Chain,
+	"signingtime",
+	"exp",
+}
+
+// reservedUnprotectedHeaders lists the labels of the unprotected header
+// parameters set by the signer.
+var reservedUnprotectedHeaders = []interface{}{
+	headerLabelCountersignature,
+	"timestamp",
+	"timestamps",
+	"ocsp",
+	"crl",
 }
 
 // NewSigner creates a signer with the recommended signing algorithm and a
@@ -46,64 +108,225 @@ func NewSignerWithCertificateChain(alg cose.Algorithm, key crypto.Signer, certCh
 		return nil, errors.New("missing signer certificate chain")
 	}
 
+	if err := validateAlgorithm(alg, key.Public()); err != nil {
+		return nil, err
+	}
 	base, err := cose.NewSigner(alg, key)
 	if err != nil {
 		return nil, err
 	}
 
-	rawCerts := make([][]byte, 0, len(certChain))
-	for _, cert := range certChain {
-		rawCerts = append(rawCerts, cert.Raw)
-	}
 	return &Signer{
+		alg:       alg,
 		base:      base,
-		certChain: rawCerts,
+		certChain: rawCertificates(certChain),
+		certs:     certChain,
+	}, nil
+}
+
+// NewSignerWithExternalSigner creates a signer with the specified signing
+// algorithm, which signs by the external signer on behalf of the key of the
+// signing certificate. The signatures returned by the external signer are
+// verified against the signing certificate before generating the envelopes.
+func NewSignerWithExternalSigner(alg cose.Algorithm, signer ExternalSigner, certChain []*x509.Certificate) (*Signer, error) {
+	if signer == nil {
+		return nil, errors.New("nil external signer")
+	}
+	if len(certChain) == 0 {
+		return nil, errors.New("missing signer certificate chain")
+	}
+	if err := validateAlgorithm(alg, certChain[0].PublicKey); err != nil {
+		return nil, err
+	}
+	return &Signer{
+		alg:       alg,
+		external:  signer,
+		certChain: rawCertificates(certChain),
+		certs:     certChain,
 	}, nil
 }
 
+// rawCertificates returns the DER encoded certificates.
+func rawCertificates(certs []*x509.Certificate) [][]byte {
+	rawCerts := make([][]byte, 0, len(certs))
+	for _, cert := range certs {
+		rawCerts = append(rawCerts, cert.Raw)
+	}
+	return rawCerts
+}
+
 // Sign signs the artifact described by its descriptor, and returns the
 // signature.
 func (s *Signer) Sign(ctx context.Context, desc notation.Descriptor, opts notation.SignOptions) ([]byte, error) {
-	if err := opts.Validate(); err != nil {
+	payload, err := json.Marshal(desc)
+	if err != nil {
+		return nil, err
+	}
+	return s.sign(ctx, payload, artifactspec.MediaTypeDescriptor, false, opts)
+}
+
+// SignBlob signs the arbitrary content, and returns the signature with the
+// payload detached. The content is not copied into the signature and is
+// required for verification.
+// Reference: RFC 9052 4.1 Signing with One or More Signers.
+func (s *Signer) SignBlob(ctx context.Context, content []byte, opts notation.SignOptions) ([]byte, error) {
+	if content == nil {
+		content = []byte{}
+	}
+	return s.sign(ctx, content, MediaTypeBlob, true, opts)
+}
+
+// SignPayload signs the payload of the specified content type, and returns
+// the signature with the payload embedded. It is used to sign the payload
+// prepared by the host of the Notation plugin protocol.
+func (s *Signer) SignPayload(ctx context.Context, payload []byte, contentType string, opts notation.SignOptions) ([]byte, error) {
+	if len(payload) == 0 {
+		return nil, errors.New("missing payload")
+	}
+	return s.sign(ctx, payload, contentType, false, opts)
+}
+
+// sign signs the payload of the specified content type, and returns the
+// signature. The payload is omitted from the signature if detached.
+func (s *Signer) sign(ctx context.Context, payload []byte, contentType string, detached bool, opts notation.SignOptions) ([]byte, error) {
+	if s.base == nil && s.external == nil {
+		return nil, errors.New("signer has no signing key: prepare the signing request and attach the signature instead")
+	}
+	msg, err := s.newMessage(payload, contentType, opts)
+	if err != nil {
 		return nil, err
 	}
 
 	// generate COSE signature
-	msg := cose.NewSign1Message()
-	payload, err := json.Marshal(desc)
+	if s.external != nil {
+		if err := s.signExternal(ctx, msg); err != nil {
+			return nil, err
+		}
+	} else if err := msg.Sign(rand.Reader, nil, s.base); err != nil {
+		return nil, err
+	}
+	return s.finalize(ctx, msg, detached, opts)
+}
+
+// newMessage creates the COSE_Sign1 message of the payload with the headers
+// populated.
+func (s *Signer) newMessage(payload []byte, contentType string, opts notation.SignOptions) (*cose.Sign1Message, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	critical, err := s.criticalHeaders()
 	if err != nil {
 		return nil, err
 	}
+
+	msg := cose.NewSign1Message()
 	msg.Payload = payload
 	msg.Headers.Protected = cose.ProtectedHeader{
-		cose.HeaderLabelAlgorithm: s.base.Algorithm(),
-		cose.HeaderLabelCritical: []interface{}{
-			cose.HeaderLabelContentType,
-		},
-		cose.HeaderLabelContentType: artifactspec.MediaTypeDescriptor,
+		cose.HeaderLabelAlgorithm:   s.alg,
+		cose.HeaderLabelCritical:    append([]interface{}{cose.HeaderLabelContentType}, critical...),
+		cose.HeaderLabelContentType: contentType,
 		cose.HeaderLabelX5Chain:     s.certChain,
-		"signingtime":               time.Now(),
+		"signingtime":               now(s.Clock),
 	}
 	if !opts.Expiry.IsZero() {
 		msg.Headers.Protected["exp"] = opts.Expiry.Unix()
 	}
-	if err := msg.Sign(rand.Reader, nil, s.base); err != nil {
+	if err := mergeHeader(msg.Headers.Protected, s.ProtectedHeaders, reservedProtectedHeaders); err != nil {
 		return nil, err
 	}
+	if err := mergeHeader(msg.Headers.Unprotected, s.UnprotectedHeaders, reservedUnprotectedHeaders); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
 
-	// timestamp signature
-	if opts.TSA != nil {
-		token, err := timestampSignature(ctx, msg.Signature, opts.TSA, opts.TSAVerifyOptions)
-		if err != nil {
-			return nil, fmt.Errorf("timestamp failed: %w", err)
+// criticalHeaders returns the labels of the additional protected header
+// parameters marked critical.
+func (s *Signer) criticalHeaders() ([]interface{}, error) {
+	for _, label := range s.CriticalHeaders {
+		if _, ok := s.ProtectedHeaders[label]; !ok {
+			return nil, fmt.Errorf("critical header %v not present in the protected headers", label)
 		}
-		msg.Headers.Unprotected["timestamp"] = token
+	}
+	return s.CriticalHeaders, nil
+}
+
+// finalize timestamps the signed message, staples the revocation data, and
+// encodes the message. The payload is omitted from the signature if detached.
+func (s *Signer) finalize(ctx context.Context, msg *cose.Sign1Message, detached bool, opts notation.SignOptions) ([]byte, error) {
+	if err := s.stampSignature(ctx, msg.Signature, msg.Headers.Unprotected, opts); err != nil {
+		return nil, err
+	}
+
+	// detach payload
+	if detached {
+		msg.Payload = nil
 	}
 
 	// encode in CBOR
 	return msg.MarshalCBOR()
 }
 
+// stampSignature timestamps the signature with the TSAs of the timestamp
+// policy or in the options, and staples the revocation data of the certificate
+// chain in the unprotected header of the signature.
+func (s *Signer) stampSignature(ctx context.Context, sig []byte, header cose.UnprotectedHeader, opts notation.SignOptions) error {
+	// timestamp signature
+	if s.Timestamping != nil {
+		tokens, err := s.Timestamping.timestamp(ctx, sig, opts.TSAVerifyOptions)
+		if err != nil {
+			return fmt.Errorf("timestamp failed: %w", err)
+		}
+		if len(tokens) == 1 {
+			header["timestamp"] = tokens[0]
+		} else {
+			header["timestamps"] = tokens
+		}
+	} else if opts.TSA != nil {
+		token, err := timestampSignature(ctx, sig, opts.TSA, opts.TSAVerifyOptions)
+		if err != nil {
+			return fmt.Errorf("timestamp failed: %w", err)
+		}
+		header["timestamp"] = token
+	}
+
+	// staple revocation data
+	if s.RevocationFetcher != nil {
+		data, err := FetchRevocationData(ctx, s.RevocationFetcher, s.certs)
+		if err != nil {
+			return fmt.Errorf("revocation data fetch failed: %w", err)
+		}
+		if len(data.OCSPResponses) > 0 {
+			header["ocsp"] = data.OCSPResponses
+		}
+		if len(data.CRLs) > 0 {
+			header["crl"] = data.CRLs
+		}
+	}
+	return nil
+}
+
+// signExternal signs the message by the external signer, and verifies the
+// returned signature against the signing certificate.
+func (s *Signer) signExternal(ctx context.Context, msg *cose.Sign1Message) error {
+	if err := msg.Sign(rand.Reader, nil, &contextSigner{
+		ctx:    ctx,
+		alg:    s.alg,
+		signer: s.external,
+	}); err != nil {
+		return fmt.Errorf("external signer failed: %w", err)
+	}
+	verifier, err := cose.NewVerifier(s.alg, s.certs[0].PublicKey)
+	if err != nil {
+		return err
+	}
+	if err := msg.Verify(nil, verifier); err != nil {
+		return fmt.Errorf("external signer returned a signature not matching the signing certificate: %w", ErrInvalidSignature)
+	}
+	return nil
+}
+
 // timestampSignature sends a request to the TSA for timestamping the signature.
 func timestampSignature(ctx context.Context, sig []byte, tsa timestamp.Timestamper, opts x509.VerifyOptions) ([]byte, error) {
 	// timestamp the signature
@@ -121,9 +344,23 @@ func timestampSignature(ctx context.Context, sig []byte, tsa timestamp.Timestamp
 	tokenBytes := resp.TokenBytes()
 
 	// verify the timestamp signature
-	if _, err := verifyTimestamp(sig, tokenBytes, opts); err != nil {
+	if _, _, err := verifyTimestamp(sig, tokenBytes, opts); err != nil {
 		return nil, err
 	}
 
 	return tokenBytes, nil
 }
+
+// mergeHeader adds the additional parameters to the header, and rejects the
+// parameters with reserved labels.
+func mergeHeader(header, params map[interface{}]interface{}, reserved []interface{}) error {
+	for label, value := range params {
+		for _, reservedLabel := range reserved {
+			if label == reservedLabel {
+				return fmt.Errorf("header %v is reserved by the signer", label)
+			}
+		}
+		header[label] = value
+	}
+	return nil
+}
@@ -2,10 +2,13 @@ package cose
 
 import (
 	"context"
+	"crypto"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"errors"
 	"math"
 	"math/big"
 	"testing"
@@ -14,6 +17,7 @@ import (
 	"github.com/notaryproject/notation-go"
 	"github.com/notaryproject/notation-go/crypto/timestamp/timestamptest"
 	"github.com/opencontainers/go-digest"
+	"github.com/veraison/go-cose"
 )
 
 func TestSignerInterface(t *testing.T) {
@@ -114,6 +118,130 @@ func TestSignWithoutExpiry(t *testing.T) {
 	}
 }
 
+func TestSignWithEd25519(t *testing.T) {
+	// sign with key
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey() error = %v", err)
+	}
+	cert, err := generateCertificate(key)
+	if err != nil {
+		t.Fatalf("generateCertificate() error = %v", err)
+	}
+	s, err := NewSigner(key, []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	sig, err := s.Sign(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	// basic verification
+	v := NewVerifier()
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	v.VerifyOptions.Roots = roots
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+}
+
+func TestSignBlob(t *testing.T) {
+	// sign with key
+	key, cert, err := generateKeyCertPair()
+	if err != nil {
+		t.Fatalf("generateKeyCertPair() error = %v", err)
+	}
+	s, err := NewSigner(key, []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+
+	ctx := context.Background()
+	content := []byte("hello world")
+	_, sOpts := generateSigningContent(nil)
+	sig, err := s.SignBlob(ctx, content, sOpts)
+	if err != nil {
+		t.Fatalf("SignBlob() error = %v", err)
+	}
+
+	// payload should be detached
+	msg := &cose.Sign1Message{}
+	if err := msg.UnmarshalCBOR(sig); err != nil {
+		t.Fatalf("Sign1Message.UnmarshalCBOR() error = %v", err)
+	}
+	if msg.Payload != nil {
+		t.Errorf("SignBlob() payload = %v, want nil", msg.Payload)
+	}
+
+	// basic verification
+	v := NewVerifier()
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	v.VerifyOptions.Roots = roots
+	if err := v.VerifyBlob(ctx, sig, content, notation.VerifyOptions{}); err != nil {
+		t.Fatalf("VerifyBlob() error = %v", err)
+	}
+}
+
+func TestSignWithHeaders(t *testing.T) {
+	// sign with key
+	key, cert, err := generateKeyCertPair()
+	if err != nil {
+		t.Fatalf("generateKeyCertPair() error = %v", err)
+	}
+	s, err := NewSignerWithCertificateChain(cose.AlgorithmPS384, key, []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatalf("NewSignerWithCertificateChain() error = %v", err)
+	}
+	s.ProtectedHeaders = cose.ProtectedHeader{
+		cose.HeaderLabelKeyID: []byte("key-1"),
+	}
+	s.UnprotectedHeaders = cose.UnprotectedHeader{
+		"note": "hello",
+	}
+
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	sig, err := s.Sign(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	// verify headers
+	v := NewVerifier()
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	v.VerifyOptions.Roots = roots
+	result, err := v.VerifyWithResult(ctx, sig, notation.VerifyOptions{})
+	if err != nil {
+		t.Fatalf("VerifyWithResult() error = %v", err)
+	}
+	if result.Algorithm != cose.AlgorithmPS384 {
+		t.Errorf("VerifyWithResult() Algorithm = %v, want %v", result.Algorithm, cose.AlgorithmPS384)
+	}
+	if got := result.UnprotectedHeaders["note"]; got != "hello" {
+		t.Errorf("VerifyWithResult() UnprotectedHeaders[note] = %v, want %v", got, "hello")
+	}
+
+	// reserved headers cannot be overridden
+	s.ProtectedHeaders = cose.ProtectedHeader{
+		cose.HeaderLabelContentType: "text/plain",
+	}
+	if _, err := s.Sign(ctx, desc, sOpts); err == nil {
+		t.Error("Sign() error = nil, want error for reserved header")
+	}
+
+	// incompatible algorithm override
+	if _, err := NewSignerWithCertificateChain(cose.AlgorithmES256, key, []*x509.Certificate{cert}); !errors.Is(err, ErrAlgorithmMismatch) {
+		t.Errorf("NewSignerWithCertificateChain() error = %v, want %v", err, ErrAlgorithmMismatch)
+	}
+}
+
 // generateSigningContent generates common signing content with options for testing.
 func generateSigningContent(tsa *timestamptest.TSA) (notation.Descriptor, notation.SignOptions) {
 	content := "hello world"
@@ -145,10 +273,18 @@ func generateKeyCertPair() (*rsa.PrivateKey, *x509.Certificate, error) {
 	if err != nil {
 		return nil, nil, err
 	}
+	cert, err := generateCertificate(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, cert, nil
+}
 
+// generateCertificate generates a self-signed test certificate for the key.
+func generateCertificate(key crypto.Signer) (*x509.Certificate, error) {
 	serialNumber, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	now := time.Now()
 	template := x509.Certificate{
@@ -164,11 +300,7 @@ func generateKeyCertPair() (*rsa.PrivateKey, *x509.Certificate, error) {
 	}
 	certBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, key.Public(), key)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	cert, err := x509.ParseCertificate(certBytes)
-	if err != nil {
-		return nil, nil, err
-	}
-	return key, cert, nil
+	return x509.ParseCertificate(certBytes)
 }
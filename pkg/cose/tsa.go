@@ -0,0 +1,188 @@
+package cose
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/notaryproject/notation-go/crypto/timestamp"
+	"github.com/veraison/go-cose"
+)
+
+// defaultTimestampBackoff is the delay before the first retry of a TSA if the
+// timestamp policy does not specify it.
+const defaultTimestampBackoff = time.Second
+
+// TimestampPolicy configures the signer to timestamp the signatures with
+// multiple timestamping authorities.
+type TimestampPolicy struct {
+	// TSAs lists the timestamping authorities in the order of preference.
+	TSAs []timestamp.Timestamper
+
+	// Retries is the number of retries of a TSA before failing over to the
+	// next TSA. Only transport failures and server errors are retried, such as
+	// the TSAUnavailableError of the timestampers created by
+	// NewHTTPTimestamper.
+	Retries int
+
+	// Backoff is the delay before the first retry of a TSA, which is doubled
+	// for each subsequent retry.
+	// If not present, the first retry is delayed by a second.
+	Backoff time.Duration
+
+	// Quorum is the number of timestamps to be collected from distinct TSAs,
+	// which are all stored in the unprotected header.
+	// If not greater than one, only the timestamp of the first TSA succeeded
+	// is stored.
+	Quorum int
+}
+
+// Validate validates the timestamp policy.
+func (p *TimestampPolicy) Validate() error {
+	if len(p.TSAs) == 0 {
+		return errors.New("timestamp policy: no TSA")
+	}
+	if p.Retries < 0 {
+		return fmt.Errorf("timestamp policy: negative retries: %d", p.Retries)
+	}
+	if p.Backoff < 0 {
+		return fmt.Errorf("timestamp policy: negative backoff: %v", p.Backoff)
+	}
+	if p.Quorum > len(p.TSAs) {
+		return fmt.Errorf("timestamp policy: quorum %d exceeds the number of TSAs %d", p.Quorum, len(p.TSAs))
+	}
+	return nil
+}
+
+// timestamp timestamps the signature by the TSAs in order until the quorum is
+// reached, and returns the timestamp tokens.
+func (p *TimestampPolicy) timestamp(ctx context.Context, sig []byte, opts x509.VerifyOptions) ([][]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	quorum := p.Quorum
+	if quorum < 1 {
+		quorum = 1
+	}
+	var tokens [][]byte
+	var errs []string
+	for _, tsa := range p.TSAs {
+		token, err := p.timestampWithRetries(ctx, sig, tsa, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			errs = append(errs, err.Error())
+			continue
+		}
+		tokens = append(tokens, token)
+		if len(tokens) == quorum {
+			return tokens, nil
+		}
+	}
+	return nil, fmt.Errorf("%d of %d timestamps obtained: %s", len(tokens), quorum, strings.Join(errs, "; "))
+}
+
+// timestampWithRetries timestamps the signature by the TSA, and retries with
+// exponential backoff on transport failures and server errors. Other failures,
+// such as a rejected request or an invalid timestamp, fail over to the next
+// TSA immediately.
+func (p *TimestampPolicy) timestampWithRetries(ctx context.Context, sig []byte, tsa timestamp.Timestamper, opts x509.VerifyOptions) ([]byte, error) {
+	backoff := p.Backoff
+	if backoff == 0 {
+		backoff = defaultTimestampBackoff
+	}
+	for retry := 0; ; retry++ {
+		token, err := timestampSignature(ctx, sig, tsa, opts)
+		if err == nil || retry >= p.Retries || !isRetryable(err) {
+			return token, err
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// isRetryable reports whether the timestamping failure is transient, which is
+// a TSAUnavailableError or a network error.
+func isRetryable(err error) bool {
+	var unavailable *TSAUnavailableError
+	if errors.As(err, &unavailable) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// NewHTTPTimestamper creates a timestamper of the TSA endpoint over HTTP, which
+// reports transport failures and server errors as TSAUnavailableError.
+// http.DefaultTransport is used if the round tripper is nil.
+func NewHTTPTimestamper(rt http.RoundTripper, endpoint string) (timestamp.Timestamper, error) {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return timestamp.NewHTTPTimestamper(&tsaTransport{rt: rt}, endpoint)
+}
+
+// tsaTransport marks the transport failures and the server errors of the TSA
+// as TSAUnavailableError.
+type tsaTransport struct {
+	rt http.RoundTripper
+}
+
+// RoundTrip sends the request to the TSA.
+func (t *tsaTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.rt.RoundTrip(req)
+	if err != nil {
+		if req.Context().Err() != nil {
+			return nil, err
+		}
+		return nil, &TSAUnavailableError{Err: err}
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		resp.Body.Close()
+		return nil, &TSAUnavailableError{Err: fmt.Errorf("unexpected status: %s", resp.Status)}
+	}
+	return resp, nil
+}
+
+// timestampsFromHeader reads the timestamp tokens from the unprotected header,
+// which holds a single token in `timestamp` or the tokens of a quorum in
+// `timestamps`.
+func timestampsFromHeader(header cose.UnprotectedHeader) ([][]byte, error) {
+	var tokens [][]byte
+	if token, ok := header["timestamp"].([]byte); ok {
+		tokens = append(tokens, token)
+	}
+	value, ok := header["timestamps"]
+	if !ok {
+		return tokens, nil
+	}
+	invalid := &EnvelopeError{
+		Label: "timestamps",
+		Value: value,
+		Err:   errors.New("invalid timestamps"),
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, invalid
+	}
+	for _, item := range items {
+		token, ok := item.([]byte)
+		if !ok {
+			return nil, invalid
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
@@ -0,0 +1,535 @@
+package cose
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/notaryproject/notation-go"
+	"github.com/notaryproject/notation-go/crypto/timestamp"
+	"github.com/veraison/go-cose"
+)
+
+func TestTimestampFailover(t *testing.T) {
+	tsas, tsaRoots := newTestTSAs(t, 2)
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	sOpts.TSAVerifyOptions.Roots = tsaRoots
+	signer, cert := newTestSigner(t)
+
+	// retry the flaky TSA
+	flaky := &flakyTimestamper{Timestamper: tsas[0], failures: 2}
+	signer.Timestamping = &TimestampPolicy{
+		TSAs:    []timestamp.Timestamper{flaky, tsas[1]},
+		Retries: 2,
+		Backoff: time.Millisecond,
+	}
+	sig, err := signer.Sign(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if flaky.attempts != 3 {
+		t.Errorf("TSA attempts = %d, want 3", flaky.attempts)
+	}
+	v := NewVerifier()
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	v.VerifyOptions.Roots = roots
+	v.TSAVerifyOptions.Roots = tsaRoots
+	v.EnforceExpiryValidation = true
+	result, err := v.VerifyWithResult(ctx, sig, notation.VerifyOptions{})
+	if err != nil {
+		t.Fatalf("VerifyWithResult() error = %v", err)
+	}
+	if !result.TimestampAuthority.Equal(tsas[0].Certificate()) {
+		t.Errorf("VerifyWithResult() TimestampAuthority = %v, want the first TSA", result.TimestampAuthority.Subject)
+	}
+
+	// fail over to the next TSA
+	flaky = &flakyTimestamper{Timestamper: tsas[0], failures: 2}
+	signer.Timestamping.TSAs[0] = flaky
+	signer.Timestamping.Retries = 1
+	if sig, err = signer.Sign(ctx, desc, sOpts); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if flaky.attempts != 2 {
+		t.Errorf("TSA attempts = %d, want 2", flaky.attempts)
+	}
+	if result, err = v.VerifyWithResult(ctx, sig, notation.VerifyOptions{}); err != nil {
+		t.Fatalf("VerifyWithResult() error = %v", err)
+	}
+	if !result.TimestampAuthority.Equal(tsas[1].Certificate()) {
+		t.Errorf("VerifyWithResult() TimestampAuthority = %v, want the second TSA", result.TimestampAuthority.Subject)
+	}
+
+	// all TSAs failed
+	signer.Timestamping.TSAs = []timestamp.Timestamper{
+		&flakyTimestamper{Timestamper: tsas[0], failures: 2},
+	}
+	if _, err := signer.Sign(ctx, desc, sOpts); err == nil {
+		t.Error("Sign() error = nil, want error for failed TSAs")
+	}
+}
+
+func TestTimestampQuorum(t *testing.T) {
+	tsas, tsaRoots := newTestTSAs(t, 3)
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	sOpts.TSAVerifyOptions.Roots = tsaRoots
+	signer, cert := newTestSigner(t)
+	signer.Timestamping = &TimestampPolicy{
+		TSAs: []timestamp.Timestamper{
+			&flakyTimestamper{Timestamper: tsas[0], failures: 1},
+			tsas[1],
+			tsas[2],
+		},
+		Quorum: 2,
+	}
+	sig, err := signer.Sign(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	msg := cose.NewSign1Message()
+	if err := msg.UnmarshalCBOR(sig); err != nil {
+		t.Fatalf("Sign1Message.UnmarshalCBOR() error = %v", err)
+	}
+	if tokens, err := timestampsFromHeader(msg.Headers.Unprotected); err != nil || len(tokens) != 2 {
+		t.Fatalf("timestampsFromHeader() = %d tokens, %v, want 2 tokens", len(tokens), err)
+	}
+
+	// verify the expired certificate by the timestamps
+	v := NewVerifier()
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	v.VerifyOptions.Roots = roots
+	v.VerifyOptions.CurrentTime = time.Now().Add(48 * time.Hour)
+	v.TSAVerifyOptions.Roots = tsaRoots
+	v.TimestampQuorum = 2
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		quorum   int
+		tsaRoots []*x509.Certificate
+	}{
+		{
+			name:   "quorum not met",
+			quorum: 3,
+		},
+		{
+			name:     "untrusted TSA",
+			quorum:   2,
+			tsaRoots: []*x509.Certificate{tsas[1].Certificate()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := *v
+			v.TimestampQuorum = tt.quorum
+			if tt.tsaRoots != nil {
+				v.TSAVerifyOptions.Roots = x509.NewCertPool()
+				for _, cert := range tt.tsaRoots {
+					v.TSAVerifyOptions.Roots.AddCert(cert)
+				}
+			}
+			if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); !errors.Is(err, ErrTimestampInvalid) {
+				t.Errorf("Verify() error = %v, want %v", err, ErrTimestampInvalid)
+			}
+		})
+	}
+
+	// quorum not reachable
+	signer.Timestamping.Quorum = 4
+	if _, err := signer.Sign(ctx, desc, sOpts); err == nil {
+		t.Error("Sign() error = nil, want error for invalid quorum")
+	}
+}
+
+func TestTimestampRetryUnavailable(t *testing.T) {
+	tsas, tsaRoots := newTestTSAs(t, 1)
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	sOpts.TSAVerifyOptions.Roots = tsaRoots
+	signer, _ := newTestSigner(t)
+
+	var requests int
+	var status int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var req timestamp.Request
+		if err := req.UnmarshalBinary(body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		resp, err := tsas[0].Timestamp(r.Context(), &req)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		// Response.MarshalBinary cannot encode the pointer receiver
+		respBytes, err := asn1.Marshal(*resp)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/timestamp-reply")
+		w.Write(respBytes)
+	}))
+	defer server.Close()
+	tsa, err := NewHTTPTimestamper(nil, server.URL)
+	if err != nil {
+		t.Fatalf("NewHTTPTimestamper() error = %v", err)
+	}
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	unreachable, err := NewHTTPTimestamper(nil, closed.URL)
+	if err != nil {
+		t.Fatalf("NewHTTPTimestamper() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		tsa      timestamp.Timestamper
+		status   int
+		requests int
+		wantErr  bool
+	}{
+		{"granted", tsa, http.StatusOK, 1, false},
+		{"server error retried", tsa, http.StatusServiceUnavailable, 3, true},
+		{"client error not retried", tsa, http.StatusBadRequest, 1, true},
+		{"unreachable retried", unreachable, http.StatusOK, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests, status = 0, tt.status
+			signer.Timestamping = &TimestampPolicy{
+				TSAs:    []timestamp.Timestamper{tt.tsa},
+				Retries: 2,
+				Backoff: time.Millisecond,
+			}
+			_, err := signer.Sign(ctx, desc, sOpts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Sign() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if requests != tt.requests {
+				t.Errorf("TSA requests = %d, want %d", requests, tt.requests)
+			}
+		})
+	}
+
+	// transport failures are reported as unavailable
+	req, err := timestamp.NewRequestFromBytes([]byte("signature"))
+	if err != nil {
+		t.Fatalf("NewRequestFromBytes() error = %v", err)
+	}
+	var unavailable *TSAUnavailableError
+	if _, err := unreachable.Timestamp(ctx, req); !errors.As(err, &unavailable) {
+		t.Errorf("Timestamp() error = %v, want %T", err, unavailable)
+	}
+}
+
+func TestTimestampFailoverOnRejection(t *testing.T) {
+	tsas, tsaRoots := newTestTSAs(t, 2)
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	sOpts.TSAVerifyOptions.Roots = tsaRoots
+	signer, _ := newTestSigner(t)
+
+	// a rejected or invalid timestamp fails over without retries
+	rejecting := &flakyTimestamper{
+		Timestamper: tsas[0],
+		failures:    1,
+		err:         errors.New("request rejected"),
+	}
+	signer.Timestamping = &TimestampPolicy{
+		TSAs:    []timestamp.Timestamper{rejecting, tsas[1]},
+		Retries: 2,
+		Backoff: time.Millisecond,
+	}
+	if _, err := signer.Sign(ctx, desc, sOpts); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if rejecting.attempts != 1 {
+		t.Errorf("TSA attempts = %d, want 1", rejecting.attempts)
+	}
+}
+
+func TestTimestampQuorumRenewedTSA(t *testing.T) {
+	tsas, tsaRoots := newTestTSAs(t, 1)
+	renewed := newTestTSA(t, tsas[0].Certificate().Subject.CommonName)
+	tsaRoots.AddCert(renewed.Certificate())
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	sOpts.TSAVerifyOptions.Roots = tsaRoots
+	signer, cert := newTestSigner(t)
+	signer.Timestamping = &TimestampPolicy{
+		TSAs:   []timestamp.Timestamper{tsas[0], renewed},
+		Quorum: 2,
+	}
+	sig, err := signer.Sign(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	// the timestamps of the renewed certificates are of the same TSA
+	v := NewVerifier()
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	v.VerifyOptions.Roots = roots
+	v.VerifyOptions.CurrentTime = time.Now().Add(48 * time.Hour)
+	v.TSAVerifyOptions.Roots = tsaRoots
+	v.TimestampQuorum = 2
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); !errors.Is(err, ErrTimestampInvalid) {
+		t.Errorf("Verify() error = %v, want %v", err, ErrTimestampInvalid)
+	}
+	v.TimestampQuorum = 1
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
+
+// flakyTimestamper fails the first requests as unavailable before delegating
+// to the timestamper.
+type flakyTimestamper struct {
+	timestamp.Timestamper
+	failures int
+	attempts int
+	err      error
+}
+
+// Timestamp fails or stamps the time with the given request.
+func (t *flakyTimestamper) Timestamp(ctx context.Context, req *timestamp.Request) (*timestamp.Response, error) {
+	t.attempts++
+	if t.attempts <= t.failures {
+		if t.err != nil {
+			return nil, t.err
+		}
+		return nil, &TSAUnavailableError{Err: errors.New("service unavailable")}
+	}
+	return t.Timestamper.Timestamp(ctx, req)
+}
+
+// newTestTSAs creates the test TSAs of distinct names and the pool of their
+// certificates.
+func newTestTSAs(t *testing.T, n int) ([]*testTSA, *x509.CertPool) {
+	roots := x509.NewCertPool()
+	tsas := make([]*testTSA, 0, n)
+	for i := 0; i < n; i++ {
+		tsa := newTestTSA(t, fmt.Sprintf("TSA %d", i))
+		roots.AddCert(tsa.Certificate())
+		tsas = append(tsas, tsa)
+	}
+	return tsas, roots
+}
+
+// Object identifiers of the timestamp tokens.
+// Reference: RFC 3161 and RFC 5652.
+var (
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA256WithRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+)
+
+// testTSA is a TSA with a self-signed certificate of the given name, since
+// the TSAs of timestamptest share the same name.
+type testTSA struct {
+	key  *rsa.PrivateKey
+	cert *x509.Certificate
+}
+
+// newTestTSA creates a TSA with a self-signed certificate of the name.
+func newTestTSA(t *testing.T, name string) *testTSA {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	serialNumber, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
+	if err != nil {
+		t.Fatalf("rand.Int() error = %v", err)
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		BasicConstraintsValid: true,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate() error = %v", err)
+	}
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate() error = %v", err)
+	}
+	return &testTSA{
+		key:  key,
+		cert: cert,
+	}
+}
+
+// Certificate returns the certificate of the TSA.
+func (tsa *testTSA) Certificate() *x509.Certificate {
+	return tsa.cert
+}
+
+// Timestamp stamps the current time with the given request.
+func (tsa *testTSA) Timestamp(_ context.Context, req *timestamp.Request) (*timestamp.Response, error) {
+	info, err := asn1.Marshal(timestamp.TSTInfo{
+		Version:        1,
+		Policy:         asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 4146, 2, 3},
+		MessageImprint: req.MessageImprint,
+		SerialNumber:   big.NewInt(time.Now().UnixNano()),
+		GenTime:        time.Now().UTC().Truncate(time.Second),
+		Accuracy:       timestamp.Accuracy{Seconds: 1},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// sign the token info
+	infoDigest := sha256.Sum256(info)
+	contentType, err := asn1.MarshalWithParams([]interface{}{oidTSTInfo}, "set")
+	if err != nil {
+		return nil, err
+	}
+	messageDigest, err := asn1.MarshalWithParams([]interface{}{infoDigest[:]}, "set")
+	if err != nil {
+		return nil, err
+	}
+	attributes := []cmsAttribute{
+		{Type: oidContentType, Values: asn1.RawValue{FullBytes: contentType}},
+		{Type: oidMessageDigest, Values: asn1.RawValue{FullBytes: messageDigest}},
+	}
+	signedAttributes, err := asn1.MarshalWithParams(attributes, "set")
+	if err != nil {
+		return nil, err
+	}
+	attributesDigest := sha256.Sum256(signedAttributes)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, tsa.key, crypto.SHA256, attributesDigest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	// encode the token
+	certs := asn1.RawValue{
+		Class:      asn1.ClassContextSpecific,
+		Tag:        0,
+		IsCompound: true,
+		Bytes:      tsa.cert.Raw,
+	}
+	signed, err := asn1.Marshal(cmsSignedData{
+		Version:          3,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{{Algorithm: oidSHA256}},
+		EncapsulatedContentInfo: cmsEncapsulatedContentInfo{
+			ContentType: oidTSTInfo,
+			Content:     info,
+		},
+		Certificates: certs,
+		SignerInfos: []cmsSignerInfo{{
+			Version: 1,
+			SignerIdentifier: cmsIssuerAndSerialNumber{
+				Issuer:       asn1.RawValue{FullBytes: tsa.cert.RawIssuer},
+				SerialNumber: tsa.cert.SerialNumber,
+			},
+			DigestAlgorithm:    pkix.AlgorithmIdentifier{Algorithm: oidSHA256},
+			SignedAttributes:   attributes,
+			SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256WithRSA},
+			Signature:          signature,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	token, err := asn1.Marshal(cmsContentInfo{
+		ContentType: oidSignedData,
+		Content: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      signed,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &timestamp.Response{
+		TimeStampToken: asn1.RawValue{FullBytes: token},
+	}, nil
+}
+
+// cmsContentInfo is the CMS ContentInfo structure, whose content is
+// explicitly tagged by the caller.
+type cmsContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+// cmsSignedData is the CMS SignedData structure.
+type cmsSignedData struct {
+	Version                 int
+	DigestAlgorithms        []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapsulatedContentInfo cmsEncapsulatedContentInfo
+	Certificates            asn1.RawValue   `asn1:"optional"`
+	SignerInfos             []cmsSignerInfo `asn1:"set"`
+}
+
+// cmsEncapsulatedContentInfo is the CMS EncapsulatedContentInfo structure.
+type cmsEncapsulatedContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     []byte `asn1:"explicit,tag:0"`
+}
+
+// cmsSignerInfo is the CMS SignerInfo structure of version 1.
+type cmsSignerInfo struct {
+	Version            int
+	SignerIdentifier   cmsIssuerAndSerialNumber
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttributes   []cmsAttribute `asn1:"set,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+}
+
+// cmsIssuerAndSerialNumber is the CMS IssuerAndSerialNumber structure.
+type cmsIssuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+// cmsAttribute is the CMS Attribute structure.
+type cmsAttribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
@@ -2,12 +2,14 @@ package cose
 
 import (
 	"context"
+	"crypto"
 	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
 
+	"github.com/microsoft/notation-cose/pkg/trustpolicy"
 	"github.com/notaryproject/notation-go"
 	"github.com/notaryproject/notation-go/crypto/timestamp"
 	"github.com/veraison/go-cose"
@@ -18,10 +20,19 @@ const maxTimestampAccuracy = time.Minute
 
 // Verifier verifies artifacts against COSE signatures.
 type Verifier struct {
-	// ResolveAlgorithm resolves the signing algorithm used to verify the
-	// signature according to the public key in the certificate chain.
-	// If not present, `AlgorithmFromKey` will be used to pick up a recommended
-	// algorithm.
+	// AllowedAlgorithms lists the signing algorithms accepted by the verifier.
+	// The algorithm is read from the protected header of the incoming
+	// signature and must be compatible with the public key in the signing
+	// certificate.
+	// If not present, the RSASSA-PSS, ECDSA, and EdDSA algorithms recommended
+	// by `AlgorithmFromKey` are accepted.
+	AllowedAlgorithms []cose.Algorithm
+
+	// ResolveAlgorithm resolves the signing algorithm according to the public
+	// key in the certificate chain. If present and AllowedAlgorithms is empty,
+	// only the resolved algorithm is accepted.
+	//
+	// Deprecated: Use AllowedAlgorithms instead.
 	ResolveAlgorithm func(interface{}) (cose.Algorithm, error)
 
 	// EnforceExpiryValidation enforces the verifier to verify the timestamp
@@ -44,6 +55,78 @@ type Verifier struct {
 	// An empty list of `KeyUsages` in the verify options implies
 	// `ExtKeyUsageTimeStamping`.
 	TSAVerifyOptions x509.VerifyOptions
+
+	// TimestampQuorum requires the valid timestamps of at least the number of
+	// distinct TSAs when the timestamp is verified. TSAs are identified by the
+	// subject and the issuer of their certificates.
+	// If not greater than one, a single valid timestamp is required.
+	TimestampQuorum int
+
+	// IdentityConstraints pins the acceptable identities of the signing
+	// certificate if present. It is checked after the certificate chain is
+	// verified.
+	IdentityConstraints *IdentityConstraints
+
+	// Revocation checks the revocation status of the verified signing
+	// certificate chain if present.
+	Revocation *RevocationChecker
+
+	// TrustPolicy selects the trust stores, the trusted identities, and the
+	// verification level by the artifact reference in the verify options of
+	// each call if present. Signatures are rejected if the reference is not
+	// provided.
+	// The `Roots` in VerifyOptions is ignored if the trust policy is present.
+	TrustPolicy *trustpolicy.Document
+
+	// TrustStores maps the names of the trust stores referenced by the trust
+	// policy to the trusted certificates.
+	TrustStores map[string][]*x509.Certificate
+
+	// Threshold requires the COSE_Sign envelopes verified by `VerifyMulti` to
+	// be signed by the parties of multiple trust stores in TrustStores if
+	// present. It does not apply to the COSE_Sign1 signatures.
+	Threshold *ThresholdPolicy
+
+	// Countersignature requires and validates the countersignatures of the
+	// COSE_Sign1 signatures if present. Otherwise, the countersignatures are
+	// ignored.
+	Countersignature *CountersignaturePolicy
+
+	// CriticalHeaderHandlers registers the handlers of the critical header
+	// parameters understood by the caller, keyed by the header labels.
+	// Integer labels are of type int64. Signatures with critical header
+	// parameters neither processed by the verifier nor registered are
+	// rejected.
+	// Reference: RFC 9052 3.1 Common COSE Header Parameters.
+	CriticalHeaderHandlers map[interface{}]CriticalHeaderHandler
+
+	// Clock provides the time at which the signatures and the certificates
+	// are verified if present. Otherwise, the system time is used.
+	// The `CurrentTime` in VerifyOptions takes precedence over the clock for
+	// the certificate verification.
+	Clock Clock
+
+	// AllowedClockSkew tolerates the drift between the clocks of the signer
+	// and the verifier when checking the signing time and the expiry of the
+	// signatures.
+	AllowedClockSkew time.Duration
+
+	// VerifyAt verifies the signatures as of the specified time if not zero,
+	// answering whether they were valid at that time, such as for incident
+	// forensics. The certificate chain, the signing time, the expiry, and the
+	// revocation status are evaluated at that time instead of the time
+	// stamped by the TSA, and certificates revoked after that time are
+	// considered valid. The `CurrentTime` in the verify options of a single
+	// call takes precedence.
+	VerifyAt time.Time
+
+	// currentTime overrides the time at which the signatures are verified if
+	// not zero. It is set per call by the verify options.
+	currentTime time.Time
+
+	// artifactReference is the reference of the verified artifact, by which
+	// the trust policy is selected. It is set per call by the verify options.
+	artifactReference string
 }
 
 // NewVerifier creates a verifier.
@@ -56,60 +139,165 @@ func NewVerifier() *Verifier {
 // Verify verifies the signature and returns the verified descriptor and
 // metadata of the signed artifact.
 func (v *Verifier) Verify(ctx context.Context, signature []byte, opts notation.VerifyOptions) (notation.Descriptor, error) {
+	result, err := v.VerifyWithResult(ctx, signature, opts)
+	if err != nil {
+		return notation.Descriptor{}, err
+	}
+	return result.Descriptor, nil
+}
+
+// VerifyWithResult verifies the signature and returns the verification result,
+// which contains the verified descriptor as well as the signer information and
+// other metadata decoded from the signature.
+func (v *Verifier) VerifyWithResult(ctx context.Context, signature []byte, opts notation.VerifyOptions) (*VerificationResult, error) {
+	return v.VerifyWithOptions(ctx, signature, VerifyOptions{
+		VerifyOptions: opts,
+	})
+}
+
+// VerifyWithOptions verifies the signature with the options of this call, and
+// returns the verification result.
+func (v *Verifier) VerifyWithOptions(ctx context.Context, signature []byte, opts VerifyOptions) (*VerificationResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	msg, result, err := v.withOptions(opts).verify(ctx, signature, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(msg.Payload, &result.Descriptor); err != nil {
+		return nil, &EnvelopeError{Err: fmt.Errorf("invalid payload: %w", err)}
+	}
+	if err := opts.matchDescriptor(result.Descriptor); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// VerifyBlob verifies the signature with detached payload against the
+// provided content.
+func (v *Verifier) VerifyBlob(ctx context.Context, signature, content []byte, opts notation.VerifyOptions) error {
+	return v.VerifyBlobWithOptions(ctx, signature, content, VerifyOptions{
+		VerifyOptions: opts,
+	})
+}
+
+// VerifyBlobWithOptions verifies the signature with detached payload against
+// the content with the options of this call. The expected descriptor does not
+// apply to blobs.
+func (v *Verifier) VerifyBlobWithOptions(ctx context.Context, signature, content []byte, opts VerifyOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if opts.ExpectedDescriptor != nil {
+		return errors.New("expected descriptor is not applicable to blobs")
+	}
+	if content == nil {
+		content = []byte{}
+	}
+	_, _, err := v.withOptions(opts).verify(ctx, signature, content)
+	return err
+}
+
+// verify verifies the signature and returns the verified COSE message with the
+// verification result populated except the descriptor.
+// The payload embedded in the signature is verified if the detached payload
+// is nil. Otherwise, the signature must have its payload detached.
+func (v *Verifier) verify(ctx context.Context, signature, detachedPayload []byte) (*cose.Sign1Message, *VerificationResult, error) {
 	// unpack envelope
 	msg := &cose.Sign1Message{}
 	if err := msg.UnmarshalCBOR(signature); err != nil {
-		return notation.Descriptor{}, err
+		return nil, nil, &EnvelopeError{Err: err}
+	}
+	if detachedPayload != nil {
+		if msg.Payload != nil {
+			return nil, nil, &EnvelopeError{Err: errors.New("payload not detached")}
+		}
+		msg.Payload = detachedPayload
+	} else if msg.Payload == nil {
+		return nil, nil, &EnvelopeError{Err: errors.New("missing payload")}
 	}
 
 	// verify signing identity
-	verifier, err := v.verifySigner(msg)
+	result := &VerificationResult{
+		UnprotectedHeaders: msg.Headers.Unprotected,
+	}
+	if v.TrustPolicy != nil {
+		policy, err := v.matchTrustPolicy()
+		if err != nil {
+			return nil, nil, err
+		}
+		result.TrustPolicy = policy
+	}
+	verifier, err := v.verifySigner(ctx, &msg.Headers, msg.Signature, result)
 	if err != nil {
-		return notation.Descriptor{}, err
+		return nil, nil, err
 	}
 
 	// verify COSE message
-	if err := verifyMessage(verifier, msg); err != nil {
-		return notation.Descriptor{}, err
+	if err := v.verifyMessage(verifier, msg, result); err != nil {
+		return nil, nil, err
 	}
 
-	var desc notation.Descriptor
-	if err := json.Unmarshal(msg.Payload, &desc); err != nil {
-		return notation.Descriptor{}, err
+	// verify countersignatures
+	if v.Countersignature != nil {
+		if err := v.verifyCountersignatures(ctx, msg, result); err != nil {
+			return nil, nil, err
+		}
 	}
-	return desc, nil
+	return msg, result, nil
 }
 
-// verifySigner verifies the signing identity and returns the verifier for
-//signature verification.
-func (v *Verifier) verifySigner(msg *cose.Sign1Message) (cose.Verifier, error) {
-	rawCertChain, _ := msg.Headers.Protected[cose.HeaderLabelX5Chain].([]interface{})
-	if len(rawCertChain) == 0 {
-		return nil, errors.New("signer certificates not found")
-	}
-	certChain := make([][]byte, 0, len(rawCertChain))
-	for _, rawCert := range rawCertChain {
-		cert, ok := rawCert.([]byte)
-		if !ok {
-			return nil, errors.New("invalid signer certificate chain")
+// verifySigner verifies the signing identity in the headers of the signature
+// and returns the verifier for signature verification.
+func (v *Verifier) verifySigner(ctx context.Context, headers *cose.Headers, sig []byte, result *VerificationResult) (cose.Verifier, error) {
+	alg, err := headers.Protected.Algorithm()
+	if err != nil {
+		return nil, &EnvelopeError{
+			Label: cose.HeaderLabelAlgorithm,
+			Value: headers.Protected[cose.HeaderLabelAlgorithm],
+			Err:   err,
 		}
-		certChain = append(certChain, cert)
 	}
 
-	timestamp, _ := msg.Headers.Unprotected["timestamp"].([]byte)
-	return v.verifySignerFromCertChain(certChain, timestamp, msg.Signature)
+	certChain, err := certChainFromHeader(headers.Protected)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamps, err := timestampsFromHeader(headers.Unprotected)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := v.verifySignerFromCertChain(alg, certChain, timestamps, sig, result)
+	if err != nil {
+		return nil, err
+	}
+
+	// check revocation status
+	if v.Revocation != nil {
+		if err := v.checkRevocation(ctx, headers, result); err != nil {
+			return nil, err
+		}
+	}
+	return verifier, nil
 }
 
 // verifySignerFromCertChain verifies the signing identity from the provided
-// certificate chain and returns the verifier. The first certificate of the
-// certificate chain contains the key, which used to sign the artifact.
-func (v *Verifier) verifySignerFromCertChain(certChain [][]byte, timeStampToken, sig []byte) (cose.Verifier, error) {
+// certificate chain and returns the verifier for the signing algorithm. The
+// first certificate of the certificate chain contains the key, which used to
+// sign the artifact.
+func (v *Verifier) verifySignerFromCertChain(alg cose.Algorithm, certChain, timestamps [][]byte, sig []byte, result *VerificationResult) (cose.Verifier, error) {
 	// prepare for certificate verification
 	certs := make([]*x509.Certificate, 0, len(certChain))
 	for _, certBytes := range certChain {
 		cert, err := x509.ParseCertificate(certBytes)
 		if err != nil {
-			return nil, err
+			return nil, &EnvelopeError{
+				Label: cose.HeaderLabelX5Chain,
+				Value: certBytes,
+				Err:   err,
+			}
 		}
 		certs = append(certs, cert)
 	}
@@ -119,110 +307,403 @@ func (v *Verifier) verifySignerFromCertChain(certChain [][]byte, timeStampToken,
 	}
 	verifyOpts := v.VerifyOptions
 	verifyOpts.Intermediates = intermediates
+	if !v.VerifyAt.IsZero() {
+		verifyOpts.CurrentTime = v.now()
+	} else if verifyOpts.CurrentTime.IsZero() && v.Clock != nil {
+		verifyOpts.CurrentTime = v.Clock.Now()
+	}
 	if len(verifyOpts.KeyUsages) == 0 {
 		verifyOpts.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
 	}
+	if result.TrustPolicy != nil {
+		roots, err := v.trustStoreRoots(result.TrustPolicy)
+		if err != nil {
+			return nil, err
+		}
+		verifyOpts.Roots = roots
+	}
 
 	// verify the signing certificate
-	checkTimestamp := v.EnforceExpiryValidation
 	cert := certs[0]
-	if _, err := cert.Verify(verifyOpts); err != nil {
-		if certErr, ok := err.(x509.CertificateInvalidError); !ok || certErr.Reason != x509.Expired {
+	chain, err := v.verifyCertificate(cert, verifyOpts, timestamps, sig, result)
+	if err != nil {
+		check := trustpolicy.CheckAuthenticity
+		if errors.Is(err, ErrTimestampInvalid) {
+			check = trustpolicy.CheckAuthenticTimestamp
+		}
+		if err := result.enforce(check, err); err != nil {
 			return nil, err
 		}
+		chain = certs
+	}
+	if result.TrustPolicy != nil {
+		if err := result.TrustPolicy.VerifyIdentity(cert); err != nil {
+			err = &CertificateError{Certificate: cert, Err: err}
+			if err := result.enforce(trustpolicy.CheckAuthenticity, err); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if v.IdentityConstraints != nil {
+		if err := v.IdentityConstraints.Check(cert); err != nil {
+			if err := result.enforce(trustpolicy.CheckAuthenticity, err); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// verify signing method
+	if err := v.verifyAlgorithm(alg, cert.PublicKey); err != nil {
+		return nil, err
+	}
+	result.Algorithm = alg
+	result.CertificateChain = chain
+	return cose.NewVerifier(alg, cert.PublicKey)
+}
+
+// verifyCertificate verifies the signing certificate, and returns the verified
+// chain to the trusted root. The certificate is verified at the time stamped
+// by the TSA if it is expired or the expiry validation is enforced, unless the
+// verifier verifies as of a historical time.
+func (v *Verifier) verifyCertificate(cert *x509.Certificate, verifyOpts x509.VerifyOptions, timestamps [][]byte, sig []byte, result *VerificationResult) ([]*x509.Certificate, error) {
+	checkTimestamp := v.EnforceExpiryValidation && v.VerifyAt.IsZero()
+	chains, err := cert.Verify(verifyOpts)
+	if err != nil {
+		if !v.VerifyAt.IsZero() {
+			return nil, &CertificateError{Certificate: cert, Err: err}
+		}
+		if certErr, ok := err.(x509.CertificateInvalidError); !ok || certErr.Reason != x509.Expired {
+			return nil, &CertificateError{Certificate: cert, Err: err}
+		}
 
 		// verification failed due to expired certificate
 		checkTimestamp = true
 	}
 	if checkTimestamp {
-		stampedTime, err := v.verifyTimestamp(timeStampToken, sig)
+		stampedTime, tsaCerts, err := v.verifyTimestamp(timestamps, sig)
 		if err != nil {
 			return nil, err
 		}
 		verifyOpts.CurrentTime = stampedTime
-		if _, err := cert.Verify(verifyOpts); err != nil {
-			return nil, err
+		chains, err = cert.Verify(verifyOpts)
+		if err != nil {
+			return nil, &CertificateError{Certificate: cert, Err: err}
 		}
+		result.TimestampTime = stampedTime
+		result.TimestampAuthority = tsaCerts[0]
 	}
+	return chains[0], nil
+}
 
-	// resolve signing method
-	resolveAlgorithm := v.ResolveAlgorithm
-	if resolveAlgorithm == nil {
-		resolveAlgorithm = AlgorithmFromKey
+// matchTrustPolicy selects the trust policy by the artifact reference in the
+// verify options of the call. The reference is never taken from the signed
+// descriptor since its annotations are controlled by the signer.
+func (v *Verifier) matchTrustPolicy() (*trustpolicy.Policy, error) {
+	if v.artifactReference == "" {
+		return nil, fmt.Errorf("%w: artifact reference is required by the trust policy", ErrUntrustedSigner)
 	}
-	alg, err := resolveAlgorithm(cert.PublicKey)
+	policy, err := v.TrustPolicy.Match(v.artifactReference)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrUntrustedSigner, err)
 	}
+	return policy, nil
+}
 
-	return cose.NewVerifier(alg, cert.PublicKey)
+// trustStoreRoots returns the trusted roots in the trust stores of the trust
+// policy.
+func (v *Verifier) trustStoreRoots(policy *trustpolicy.Policy) (*x509.CertPool, error) {
+	roots := x509.NewCertPool()
+	for _, name := range policy.TrustStores {
+		certs, ok := v.TrustStores[name]
+		if !ok {
+			return nil, fmt.Errorf("trust policy %q: trust store %q not found", policy.Name, name)
+		}
+		for _, cert := range certs {
+			roots.AddCert(cert)
+		}
+	}
+	return roots, nil
+}
+
+// checkRevocation checks the revocation status of the verified certificate
+// chain. The revocation data stapled in the unprotected header is preferred if
+// it is valid at the time of the verified timestamp, or at the current time
+// otherwise, and never at the signing time claimed by the signer. The
+// revocation status is evaluated as of the historical time if the verifier
+// verifies at that time.
+func (v *Verifier) checkRevocation(ctx context.Context, headers *cose.Headers, result *VerificationResult) error {
+	stapled, err := revocationDataFromHeader(headers.Unprotected)
+	if err != nil {
+		return err
+	}
+	if !v.VerifyAt.IsZero() {
+		if err := v.Revocation.CheckAt(ctx, result.CertificateChain, stapled, v.now()); err != nil {
+			return result.enforce(trustpolicy.CheckRevocation, &CertificateError{Certificate: result.Signer(), Err: err})
+		}
+		return nil
+	}
+	// the signing time is controlled by the signer, and is never trusted for
+	// judging the freshness of the stapled revocation data.
+	at := result.TimestampTime
+	if at.IsZero() {
+		at = v.now()
+	}
+	if err := v.Revocation.CheckStapled(ctx, result.CertificateChain, stapled, at); err != nil {
+		return result.enforce(trustpolicy.CheckRevocation, &CertificateError{Certificate: result.Signer(), Err: err})
+	}
+	return nil
 }
 
-// verifyTimestamp verifies the timestamp token and returns stamped time.
-func (v *Verifier) verifyTimestamp(tokenBytes, sig []byte) (time.Time, error) {
-	return verifyTimestamp(sig, tokenBytes, v.TSAVerifyOptions)
+// verifyAlgorithm verifies the signing algorithm against the allowlist and
+// the public key of the signing certificate.
+func (v *Verifier) verifyAlgorithm(alg cose.Algorithm, key crypto.PublicKey) error {
+	allowedAlgorithms := v.AllowedAlgorithms
+	if len(allowedAlgorithms) == 0 && v.ResolveAlgorithm != nil {
+		resolved, err := v.ResolveAlgorithm(key)
+		if err != nil {
+			return err
+		}
+		allowedAlgorithms = []cose.Algorithm{resolved}
+	}
+	if len(allowedAlgorithms) == 0 {
+		allowedAlgorithms = defaultAllowedAlgorithms
+	}
+	allowed := false
+	for _, allowedAlg := range allowedAlgorithms {
+		if alg == allowedAlg {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("%w: %v", ErrAlgorithmNotAllowed, alg)
+	}
+	return validateAlgorithm(alg, key)
 }
 
-// verifyMessage verifies the COSE message against the specified verifier.
-func verifyMessage(verifier cose.Verifier, msg *cose.Sign1Message) error {
+// now returns the time at which the signatures are verified.
+func (v *Verifier) now() time.Time {
+	if !v.currentTime.IsZero() {
+		return v.currentTime
+	}
+	if !v.VerifyAt.IsZero() {
+		return v.VerifyAt
+	}
+	return now(v.Clock)
+}
+
+// verifyTimestamp verifies the timestamp tokens against the timestamp quorum,
+// and returns the latest stamped time of the valid timestamps and the signing
+// certificates of its TSA. Timestamps of the same TSA are counted once, where
+// a TSA is identified by the subject and the issuer of its certificate so that
+// the renewed certificates of a TSA do not count as distinct TSAs.
+func (v *Verifier) verifyTimestamp(tokens [][]byte, sig []byte) (time.Time, []*x509.Certificate, error) {
+	if len(tokens) == 0 {
+		return time.Time{}, nil, &TimestampError{Err: errors.New("timestamp not found")}
+	}
+	quorum := v.TimestampQuorum
+	if quorum < 1 {
+		quorum = 1
+	}
+	var stampedTime time.Time
+	var tsaCerts []*x509.Certificate
+	authorities := make(map[tsaIdentity]bool)
+	var errs []error
+	for _, token := range tokens {
+		tokenTime, certs, err := verifyTimestamp(sig, token, v.TSAVerifyOptions)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		authorities[identityOfTSA(certs[0])] = true
+		if tokenTime.After(stampedTime) {
+			stampedTime, tsaCerts = tokenTime, certs
+		}
+	}
+	if len(authorities) < quorum {
+		if len(errs) == 1 && len(tokens) == 1 {
+			return time.Time{}, nil, &TimestampError{Err: errs[0]}
+		}
+		return time.Time{}, nil, &TimestampError{
+			Err: fmt.Errorf("%d of %d timestamps verified: %v", len(authorities), quorum, errs),
+		}
+	}
+	return stampedTime, tsaCerts, nil
+}
+
+// tsaIdentity identifies a TSA by the DER encoded subject and issuer of its
+// certificate.
+type tsaIdentity struct {
+	subject string
+	issuer  string
+}
+
+// identityOfTSA returns the identity of the TSA of the certificate.
+func identityOfTSA(cert *x509.Certificate) tsaIdentity {
+	return tsaIdentity{
+		subject: string(cert.RawSubject),
+		issuer:  string(cert.RawIssuer),
+	}
+}
+
+// verifyMessage verifies the COSE message against the specified verifier, and
+// records the signing time and the expiry in the verification result.
+func (v *Verifier) verifyMessage(verifier cose.Verifier, msg *cose.Sign1Message, result *VerificationResult) error {
 	// verify signature
 	if err := msg.Verify(nil, verifier); err != nil {
+		return &SignatureError{Err: err}
+	}
+	return v.verifyAttributes(msg.Headers.Protected, result)
+}
+
+// verifyAttributes verifies the critical header parameters, the signing time,
+// and the expiry in the protected header, and records them in the
+// verification result.
+func (v *Verifier) verifyAttributes(header cose.ProtectedHeader, result *VerificationResult) error {
+	if err := v.verifyCritical(header); err != nil {
+		return err
+	}
+	signingTime, err := signingTimeFromHeader(header)
+	if err != nil {
 		return err
 	}
+	now := v.now()
+	if signingTime.After(now.Add(v.AllowedClockSkew)) {
+		return &SigningTimeError{
+			SigningTime: signingTime,
+			CurrentTime: now,
+		}
+	}
 
-	// verify attributes
-	header := msg.Headers.Protected
+	if value, ok := header["exp"]; ok {
+		unix, ok := value.(int64)
+		if !ok {
+			return &EnvelopeError{
+				Label: "exp",
+				Value: value,
+				Err:   errors.New("invalid exp"),
+			}
+		}
+		expiresAt := time.Unix(unix, 0)
+		if !now.Add(-v.AllowedClockSkew).Before(expiresAt) {
+			if err := result.enforce(trustpolicy.CheckExpiry, &SignatureExpiredError{
+				Expiry:      expiresAt,
+				CurrentTime: now,
+			}); err != nil {
+				return err
+			}
+		}
+		result.Expiry = expiresAt
+	}
+	result.SigningTime = signingTime
+	return nil
+}
+
+// signingTimeFromHeader reads the signing time from the protected header.
+func signingTimeFromHeader(header cose.ProtectedHeader) (time.Time, error) {
 	signingTimeValue, ok := header["signingtime"]
 	if !ok {
-		return errors.New("missing signingtime")
+		return time.Time{}, &EnvelopeError{
+			Label: "signingtime",
+			Err:   errors.New("missing signingtime"),
+		}
 	}
-	var signingTime time.Time
 	switch value := signingTimeValue.(type) {
 	case int64:
-		signingTime = time.Unix(value, 0)
+		return time.Unix(value, 0), nil
 	case time.Time:
-		signingTime = value
-	default:
-		return errors.New("invalid signingtime")
+		return value, nil
 	}
-	now := time.Now()
-	if signingTime.After(now) {
-		return errors.New("signature used before generated")
+	return time.Time{}, &EnvelopeError{
+		Label: "signingtime",
+		Value: signingTimeValue,
+		Err:   errors.New("invalid signingtime"),
 	}
+}
 
-	if value, ok := header["exp"]; ok {
-		unix, ok := value.(int64)
+// certChainFromHeader reads the DER encoded signer certificate chain from the
+// protected header.
+func certChainFromHeader(header cose.ProtectedHeader) ([][]byte, error) {
+	rawCertChainValue := header[cose.HeaderLabelX5Chain]
+	rawCertChain, _ := rawCertChainValue.([]interface{})
+	if len(rawCertChain) == 0 {
+		return nil, &EnvelopeError{
+			Label: cose.HeaderLabelX5Chain,
+			Value: rawCertChainValue,
+			Err:   errors.New("signer certificates not found"),
+		}
+	}
+	certChain := make([][]byte, 0, len(rawCertChain))
+	for _, rawCert := range rawCertChain {
+		cert, ok := rawCert.([]byte)
 		if !ok {
-			return errors.New("invalid exp")
+			return nil, &EnvelopeError{
+				Label: cose.HeaderLabelX5Chain,
+				Value: rawCert,
+				Err:   errors.New("invalid signer certificate chain"),
+			}
 		}
-		expiresAt := time.Unix(unix, 0)
-		if !now.Before(expiresAt) {
-			delta := now.Sub(expiresAt)
-			return fmt.Errorf("signature is expired by %v", delta)
+		certChain = append(certChain, cert)
+	}
+	return certChain, nil
+}
+
+// revocationDataFromHeader reads the stapled revocation data from the
+// unprotected header.
+func revocationDataFromHeader(header cose.UnprotectedHeader) (*RevocationData, error) {
+	data := &RevocationData{}
+	for label, list := range map[string]*[][]byte{
+		"ocsp": &data.OCSPResponses,
+		"crl":  &data.CRLs,
+	} {
+		value, ok := header[label]
+		if !ok {
+			continue
+		}
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, &EnvelopeError{
+				Label: label,
+				Value: value,
+				Err:   fmt.Errorf("invalid %s", label),
+			}
+		}
+		for _, item := range items {
+			itemBytes, ok := item.([]byte)
+			if !ok {
+				return nil, &EnvelopeError{
+					Label: label,
+					Value: item,
+					Err:   fmt.Errorf("invalid %s", label),
+				}
+			}
+			*list = append(*list, itemBytes)
 		}
 	}
-	return nil
+	return data, nil
 }
 
-// verifyTimestamp verifies the timestamp token and returns stamped time.
-func verifyTimestamp(contentBytes, tokenBytes []byte, opts x509.VerifyOptions) (time.Time, error) {
+// verifyTimestamp verifies the timestamp token and returns stamped time and
+// the signing certificates of the TSA.
+func verifyTimestamp(contentBytes, tokenBytes []byte, opts x509.VerifyOptions) (time.Time, []*x509.Certificate, error) {
 	token, err := timestamp.ParseSignedToken(tokenBytes)
 	if err != nil {
-		return time.Time{}, err
+		return time.Time{}, nil, err
 	}
-	if _, err := token.Verify(opts); err != nil {
-		return time.Time{}, err
+	tsaCerts, err := token.Verify(opts)
+	if err != nil {
+		return time.Time{}, nil, err
 	}
 	info, err := token.Info()
 	if err != nil {
-		return time.Time{}, err
+		return time.Time{}, nil, err
 	}
 	if err := info.Verify(contentBytes); err != nil {
-		return time.Time{}, err
+		return time.Time{}, nil, err
 	}
 	stampedTime, accuracy := info.Timestamp()
 	if accuracy > maxTimestampAccuracy {
-		return time.Time{}, fmt.Errorf("max timestamp accuracy exceeded: %v", accuracy)
+		return time.Time{}, nil, fmt.Errorf("max timestamp accuracy exceeded: %v", accuracy)
 	}
-	return stampedTime, nil
+	return stampedTime, tsaCerts, nil
 }
@@ -2,13 +2,25 @@ package cose
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"errors"
+	"net/url"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/microsoft/notation-cose/pkg/trustpolicy"
 	"github.com/notaryproject/notation-go"
 	"github.com/notaryproject/notation-go/crypto/timestamp/timestamptest"
+	"github.com/veraison/go-cose"
 )
 
 func TestVerifierInterface(t *testing.T) {
@@ -111,3 +123,565 @@ func TestVerifyWithTimestamp(t *testing.T) {
 		t.Errorf("Verify() Descriptor = %v, want %v", got, desc)
 	}
 }
+
+func TestVerifyWithHeaderAlgorithm(t *testing.T) {
+	// sign with a 3072-bit RSA key using PS384
+	key, err := rsa.GenerateKey(rand.Reader, 3072)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	cert, err := generateCertificate(key)
+	if err != nil {
+		t.Fatalf("generateCertificate() error = %v", err)
+	}
+	s, err := NewSignerWithCertificateChain(cose.AlgorithmPS384, key, []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatalf("NewSignerWithCertificateChain() error = %v", err)
+	}
+
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	sig, err := s.Sign(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	// verify signature
+	v := NewVerifier()
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	v.VerifyOptions.Roots = roots
+	var vOpts notation.VerifyOptions
+	got, err := v.Verify(ctx, sig, vOpts)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !got.Equal(desc) {
+		t.Errorf("Verify() Descriptor = %v, want %v", got, desc)
+	}
+
+	// should fail if the algorithm is not allowed
+	v.AllowedAlgorithms = []cose.Algorithm{cose.AlgorithmPS256}
+	if _, err := v.Verify(ctx, sig, vOpts); err == nil {
+		t.Errorf("Verify() error = %v, wantErr %v", err, true)
+	}
+
+	// the deprecated algorithm resolver restricts the allowed algorithm
+	v.AllowedAlgorithms = nil
+	v.ResolveAlgorithm = AlgorithmFromKey
+	if _, err := v.Verify(ctx, sig, vOpts); err != nil {
+		t.Errorf("Verify() with ResolveAlgorithm error = %v", err)
+	}
+	v.ResolveAlgorithm = func(interface{}) (cose.Algorithm, error) {
+		return cose.AlgorithmPS512, nil
+	}
+	if _, err := v.Verify(ctx, sig, vOpts); !errors.Is(err, ErrAlgorithmNotAllowed) {
+		t.Errorf("Verify() with ResolveAlgorithm error = %v, want %v", err, ErrAlgorithmNotAllowed)
+	}
+}
+
+func TestValidateAlgorithm(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey() error = %v", err)
+	}
+	ed25519Key, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		alg     cose.Algorithm
+		key     interface{}
+		wantErr bool
+	}{
+		{"PS256 with RSA key", cose.AlgorithmPS256, rsaKey.Public(), false},
+		{"PS512 with RSA key", cose.AlgorithmPS512, rsaKey.Public(), false},
+		{"ES384 with RSA key", cose.AlgorithmES384, rsaKey.Public(), true},
+		{"ES384 with P-384 key", cose.AlgorithmES384, ecdsaKey.Public(), false},
+		{"ES256 with P-384 key", cose.AlgorithmES256, ecdsaKey.Public(), true},
+		{"PS384 with P-384 key", cose.AlgorithmPS384, ecdsaKey.Public(), true},
+		{"EdDSA with Ed25519 key", cose.AlgorithmEd25519, ed25519Key, false},
+		{"EdDSA with RSA key", cose.AlgorithmEd25519, rsaKey.Public(), true},
+		{"ES256 with Ed25519 key", cose.AlgorithmES256, ed25519Key, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAlgorithm(tt.alg, tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateAlgorithm() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrAlgorithmMismatch) {
+				t.Errorf("validateAlgorithm() error = %v, want %v", err, ErrAlgorithmMismatch)
+			}
+		})
+	}
+}
+
+func TestVerifyWithEd25519(t *testing.T) {
+	// sign with key
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey() error = %v", err)
+	}
+	cert, err := generateCertificate(key)
+	if err != nil {
+		t.Fatalf("generateCertificate() error = %v", err)
+	}
+	s, err := NewSignerWithCertificateChain(cose.AlgorithmEd25519, key, []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatalf("NewSignerWithCertificateChain() error = %v", err)
+	}
+
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	sig, err := s.Sign(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	// verify signature
+	v := NewVerifier()
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	v.VerifyOptions.Roots = roots
+	var vOpts notation.VerifyOptions
+	got, err := v.Verify(ctx, sig, vOpts)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, desc) {
+		t.Errorf("Verify() Descriptor = %v, want %v", got, desc)
+	}
+
+	// should fail if EdDSA is not allowed
+	v.AllowedAlgorithms = []cose.Algorithm{cose.AlgorithmPS256, cose.AlgorithmES256}
+	if _, err := v.Verify(ctx, sig, vOpts); err == nil {
+		t.Errorf("Verify() error = %v, wantErr %v", err, true)
+	}
+}
+
+func TestVerifyBlob(t *testing.T) {
+	// sign with key
+	key, cert, err := generateKeyCertPair()
+	if err != nil {
+		t.Fatalf("generateKeyCertPair() error = %v", err)
+	}
+	s, err := NewSigner(key, []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+
+	ctx := context.Background()
+	content := []byte("hello world")
+	desc, sOpts := generateSigningContent(nil)
+	sig, err := s.SignBlob(ctx, content, sOpts)
+	if err != nil {
+		t.Fatalf("SignBlob() error = %v", err)
+	}
+	descSig, err := s.Sign(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	// verify signature
+	v := NewVerifier()
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	v.VerifyOptions.Roots = roots
+	var vOpts notation.VerifyOptions
+	if err := v.VerifyBlob(ctx, sig, content, vOpts); err != nil {
+		t.Fatalf("VerifyBlob() error = %v", err)
+	}
+
+	// should fail if content is tampered
+	if err := v.VerifyBlob(ctx, sig, []byte("hello world!"), vOpts); err == nil {
+		t.Errorf("VerifyBlob() error = %v, wantErr %v", err, true)
+	}
+
+	// should fail if payload is not detached
+	if err := v.VerifyBlob(ctx, descSig, content, vOpts); err == nil {
+		t.Errorf("VerifyBlob() error = %v, wantErr %v", err, true)
+	}
+
+	// should fail if verified without the detached payload
+	if _, err := v.Verify(ctx, sig, vOpts); err == nil {
+		t.Errorf("Verify() error = %v, wantErr %v", err, true)
+	}
+}
+
+func TestVerifyWithResult(t *testing.T) {
+	// prepare signer
+	key, cert, err := generateKeyCertPair()
+	if err != nil {
+		t.Fatalf("generateKeyCertPair() error = %v", err)
+	}
+	s, err := NewSigner(key, []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+
+	// configure TSA
+	tsa, err := timestamptest.NewTSA()
+	if err != nil {
+		t.Fatalf("timestamptest.NewTSA() error = %v", err)
+	}
+
+	// sign content
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(tsa)
+	sig, err := s.Sign(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	// verify signature with timestamp enforced
+	v := NewVerifier()
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	v.VerifyOptions.Roots = roots
+	v.TSAVerifyOptions.Roots = sOpts.TSAVerifyOptions.Roots
+	v.EnforceExpiryValidation = true
+	got, err := v.VerifyWithResult(ctx, sig, notation.VerifyOptions{})
+	if err != nil {
+		t.Fatalf("VerifyWithResult() error = %v", err)
+	}
+	if !reflect.DeepEqual(got.Descriptor, desc) {
+		t.Errorf("VerifyWithResult() Descriptor = %v, want %v", got.Descriptor, desc)
+	}
+	if got.Algorithm != cose.AlgorithmPS256 {
+		t.Errorf("VerifyWithResult() Algorithm = %v, want %v", got.Algorithm, cose.AlgorithmPS256)
+	}
+	if signer := got.Signer(); signer == nil || !signer.Equal(cert) {
+		t.Errorf("VerifyWithResult() Signer = %v, want %v", signer, cert)
+	}
+	if got.SigningTime.IsZero() {
+		t.Error("VerifyWithResult() SigningTime is zero")
+	}
+	if want := sOpts.Expiry.Truncate(time.Second); !got.Expiry.Equal(want) {
+		t.Errorf("VerifyWithResult() Expiry = %v, want %v", got.Expiry, want)
+	}
+	if got.TimestampTime.IsZero() {
+		t.Error("VerifyWithResult() TimestampTime is zero")
+	}
+	if got.TimestampAuthority == nil || !got.TimestampAuthority.Equal(tsa.Certificate()) {
+		t.Errorf("VerifyWithResult() TimestampAuthority = %v, want %v", got.TimestampAuthority, tsa.Certificate())
+	}
+	if _, ok := got.UnprotectedHeaders["timestamp"]; !ok {
+		t.Error("VerifyWithResult() UnprotectedHeaders missing timestamp")
+	}
+	if _, err := json.Marshal(got); err != nil {
+		t.Errorf("json.Marshal(VerificationResult) error = %v", err)
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	// prepare signer
+	key, cert, err := generateKeyCertPair()
+	if err != nil {
+		t.Fatalf("generateKeyCertPair() error = %v", err)
+	}
+	s, err := NewSigner(key, []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	sig, err := s.Sign(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	expiredOpts := sOpts
+	expiredOpts.Expiry = time.Now().Add(-time.Hour)
+	expiredSig, err := s.Sign(ctx, desc, expiredOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	tamperedSig := make([]byte, len(sig))
+	copy(tamperedSig, sig)
+	tamperedSig[len(tamperedSig)-1] ^= 0xff
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	trusted := NewVerifier()
+	trusted.VerifyOptions.Roots = roots
+	enforced := NewVerifier()
+	enforced.VerifyOptions.Roots = roots
+	enforced.EnforceExpiryValidation = true
+
+	tests := []struct {
+		name      string
+		verifier  *Verifier
+		signature []byte
+		want      error
+	}{
+		{"invalid envelope", trusted, []byte("not a signature"), ErrInvalidEnvelope},
+		{"invalid signature", trusted, tamperedSig, ErrInvalidSignature},
+		{"untrusted signer", NewVerifier(), sig, ErrUntrustedSigner},
+		{"expired signature", trusted, expiredSig, ErrSignatureExpired},
+		{"missing timestamp", enforced, sig, ErrTimestampInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.verifier.Verify(ctx, tt.signature, notation.VerifyOptions{})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// inspect offending values
+	_, err = trusted.Verify(ctx, expiredSig, notation.VerifyOptions{})
+	var expiredErr *SignatureExpiredError
+	if !errors.As(err, &expiredErr) {
+		t.Fatalf("Verify() error = %v, want %T", err, expiredErr)
+	}
+	if want := expiredOpts.Expiry.Truncate(time.Second); !expiredErr.Expiry.Equal(want) {
+		t.Errorf("SignatureExpiredError.Expiry = %v, want %v", expiredErr.Expiry, want)
+	}
+	_, err = NewVerifier().Verify(ctx, sig, notation.VerifyOptions{})
+	var certErr *CertificateError
+	if !errors.As(err, &certErr) {
+		t.Fatalf("Verify() error = %v, want %T", err, certErr)
+	}
+	if !certErr.Certificate.Equal(cert) {
+		t.Errorf("CertificateError.Certificate = %v, want %v", certErr.Certificate, cert)
+	}
+}
+
+func TestVerifyWithTrustPolicy(t *testing.T) {
+	// prepare signer
+	key, cert, err := generateKeyCertPair()
+	if err != nil {
+		t.Fatalf("generateKeyCertPair() error = %v", err)
+	}
+	_, otherCert, err := generateKeyCertPair()
+	if err != nil {
+		t.Fatalf("generateKeyCertPair() error = %v", err)
+	}
+	s, err := NewSigner(key, []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	sig, err := s.Sign(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	expiredOpts := sOpts
+	expiredOpts.Expiry = time.Now().Add(-time.Hour)
+	expiredSig, err := s.Sign(ctx, desc, expiredOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	newVerifier := func(scope string, level trustpolicy.Level, trustStore, identity string) *Verifier {
+		v := NewVerifier()
+		v.TrustPolicy = &trustpolicy.Document{
+			Version: trustpolicy.Version,
+			TrustPolicies: []trustpolicy.Policy{
+				{
+					Name:                  "test",
+					RegistryScopes:        []string{scope},
+					SignatureVerification: level,
+					TrustStores:           []string{trustStore},
+					TrustedIdentities:     []string{identity},
+				},
+			},
+		}
+		v.TrustStores = map[string][]*x509.Certificate{
+			"signer": {cert},
+			"other":  {otherCert},
+		}
+		return v
+	}
+	const scope = "test.registry.io/test"
+	const reference = scope + ":example"
+	tests := []struct {
+		name      string
+		verifier  *Verifier
+		signature []byte
+		reference string
+		want      error
+		warnings  int
+	}{
+		{"strict", newVerifier(scope, trustpolicy.LevelStrict, "signer", "x509.subject: CN=test"), sig, reference, nil, 0},
+		{"global policy", newVerifier("*", trustpolicy.LevelStrict, "signer", "*"), sig, reference, nil, 0},
+		{"missing reference", newVerifier("*", trustpolicy.LevelStrict, "signer", "*"), sig, "", ErrUntrustedSigner, 0},
+		{"unmatched scope", newVerifier("test.registry.io/other", trustpolicy.LevelStrict, "signer", "*"), sig, reference, ErrUntrustedSigner, 0},
+		{"signed annotation ignored", newVerifier(scope, trustpolicy.LevelAudit, "signer", "*"), sig, "test.registry.io/other:example", ErrUntrustedSigner, 0},
+		{"untrusted identity", newVerifier(scope, trustpolicy.LevelStrict, "signer", "x509.subject: CN=other"), sig, reference, ErrUntrustedSigner, 0},
+		{"untrusted identity audited", newVerifier(scope, trustpolicy.LevelAudit, "signer", "x509.subject: CN=other"), sig, reference, nil, 1},
+		{"untrusted root", newVerifier(scope, trustpolicy.LevelPermissive, "other", "*"), sig, reference, ErrUntrustedSigner, 0},
+		{"untrusted root audited", newVerifier(scope, trustpolicy.LevelAudit, "other", "*"), sig, reference, nil, 1},
+		{"expired signature", newVerifier(scope, trustpolicy.LevelStrict, "signer", "*"), expiredSig, reference, ErrSignatureExpired, 0},
+		{"expired signature permitted", newVerifier(scope, trustpolicy.LevelPermissive, "signer", "*"), expiredSig, reference, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.verifier.VerifyWithOptions(ctx, tt.signature, VerifyOptions{
+				ArtifactReference: tt.reference,
+			})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("VerifyWithOptions() error = %v, want %v", err, tt.want)
+			}
+			if err != nil {
+				return
+			}
+			if result.TrustPolicy == nil || result.TrustPolicy.Name != "test" {
+				t.Errorf("VerifyWithOptions() TrustPolicy = %v, want %v", result.TrustPolicy, "test")
+			}
+			if len(result.Warnings) != tt.warnings {
+				t.Errorf("VerifyWithOptions() Warnings = %v, want %d warnings", result.Warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestVerifyWithIdentityConstraints(t *testing.T) {
+	// prepare signer
+	key, cert, err := generateKeyCertPair()
+	if err != nil {
+		t.Fatalf("generateKeyCertPair() error = %v", err)
+	}
+	s, err := NewSigner(key, []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	ctx := context.Background()
+	desc, sOpts := generateSigningContent(nil)
+	sig, err := s.Sign(ctx, desc, sOpts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	// verify with satisfied constraints
+	v := NewVerifier()
+	v.VerifyOptions.Roots = roots
+	v.IdentityConstraints, err = NewIdentityConstraints(IdentityConstraintOptions{
+		Subject:      "CN=test",
+		ExtKeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		t.Fatalf("NewIdentityConstraints() error = %v", err)
+	}
+	if _, err := v.Verify(ctx, sig, notation.VerifyOptions{}); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	// verify with violated constraints
+	v.IdentityConstraints, err = NewIdentityConstraints(IdentityConstraintOptions{
+		Subject:      "CN=other",
+		DNSNames:     []string{`signer\.example\.com`},
+		ExtKeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		Policies:     []asn1.ObjectIdentifier{{2, 23, 140, 1, 4, 1}},
+	})
+	if err != nil {
+		t.Fatalf("NewIdentityConstraints() error = %v", err)
+	}
+	_, err = v.Verify(ctx, sig, notation.VerifyOptions{})
+	if !errors.Is(err, ErrUntrustedSigner) {
+		t.Fatalf("Verify() error = %v, want %v", err, ErrUntrustedSigner)
+	}
+	var identityErr *IdentityError
+	if !errors.As(err, &identityErr) {
+		t.Fatalf("Verify() error = %v, want %T", err, identityErr)
+	}
+	if got := len(identityErr.Violations); got != 3 {
+		t.Errorf("IdentityError.Violations = %v, want 3 violations", identityErr.Violations)
+	}
+}
+
+func TestIdentityConstraintsAnchored(t *testing.T) {
+	uri, err := url.Parse("https://example.com.attacker.net/builds/1")
+	if err != nil {
+		t.Fatalf("url.Parse() error = %v", err)
+	}
+	cert := &x509.Certificate{
+		DNSNames:       []string{"example.com.attacker.net"},
+		URIs:           []*url.URL{uri},
+		EmailAddresses: []string{"signer@example.com.attacker.net"},
+	}
+	tests := []struct {
+		opts IdentityConstraintOptions
+		want bool
+	}{
+		{IdentityConstraintOptions{DNSNames: []string{`example\.com`}}, false},
+		{IdentityConstraintOptions{DNSNames: []string{`attacker\.net|example\.com`}}, false},
+		{IdentityConstraintOptions{DNSNames: []string{`.*\.attacker\.net`}}, true},
+		{IdentityConstraintOptions{DNSNames: []string{`^example\.com`, `.*\.attacker\.net$`}}, true},
+		{IdentityConstraintOptions{URIs: []string{`https://example\.com/.*`}}, false},
+		{IdentityConstraintOptions{URIs: []string{`https://example\.com\.attacker\.net/.*`}}, true},
+		{IdentityConstraintOptions{EmailAddresses: []string{`.*@example\.com`}}, false},
+		{IdentityConstraintOptions{EmailAddresses: []string{`(?i)SIGNER@.*`}}, true},
+	}
+	for _, tt := range tests {
+		c, err := NewIdentityConstraints(tt.opts)
+		if err != nil {
+			t.Fatalf("NewIdentityConstraints(%+v) error = %v", tt.opts, err)
+		}
+		err = c.Check(cert)
+		if got := err == nil; got != tt.want {
+			t.Errorf("IdentityConstraints.Check() with %+v error = %v, want match %v", tt.opts, err, tt.want)
+		}
+	}
+}
+
+func TestIdentityConstraintsSubject(t *testing.T) {
+	cert := &x509.Certificate{
+		Subject: pkix.Name{
+			Country:      []string{"US"},
+			Organization: []string{"Example, Inc."},
+			CommonName:   "signer",
+		},
+	}
+	tests := []struct {
+		subject string
+		want    bool
+	}{
+		{`C=US, O=Example\, Inc., CN=signer`, true},
+		{`CN=signer,O=Example\, Inc.,C=US`, true},
+		{`CN=signer, O="Example, Inc.", C=US`, true},
+		{`CN=signer, C=US`, false},
+		{`CN=signer, O=Example\, Inc., C=US, OU=Builds`, false},
+		{`CN=signer, O=Example, C=US`, false},
+	}
+	for _, tt := range tests {
+		c, err := NewIdentityConstraints(IdentityConstraintOptions{Subject: tt.subject})
+		if err != nil {
+			t.Fatalf("NewIdentityConstraints() with subject %q error = %v", tt.subject, err)
+		}
+		err = c.Check(cert)
+		if got := err == nil; got != tt.want {
+			t.Errorf("IdentityConstraints.Check() with subject %q error = %v, want match %v", tt.subject, err, tt.want)
+		}
+	}
+}
+
+func TestNewIdentityConstraintsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts IdentityConstraintOptions
+	}{
+		{"invalid subject", IdentityConstraintOptions{Subject: "CN"}},
+		{"unsupported subject attribute", IdentityConstraintOptions{Subject: "UID=signer"}},
+		{"invalid DNS name pattern", IdentityConstraintOptions{DNSNames: []string{`(example\.com`}}},
+		{"invalid URI pattern", IdentityConstraintOptions{URIs: []string{`https://[`}}},
+		{"invalid email address pattern", IdentityConstraintOptions{EmailAddresses: []string{`*@example\.com`}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewIdentityConstraints(tt.opts); err == nil {
+				t.Error("NewIdentityConstraints() error = nil, want error")
+			}
+		})
+	}
+}
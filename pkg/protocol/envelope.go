@@ -0,0 +1,50 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MediaTypeEnvelope is the signature envelope type of COSE signatures.
+const MediaTypeEnvelope = "application/cose"
+
+// MediaTypePayload is the payload type prepared by the Notation host.
+const MediaTypePayload = "application/vnd.cncf.notary.payload.v1+json"
+
+// GenerateEnvelopeRequest is the request of the generate-envelope command.
+type GenerateEnvelopeRequest struct {
+	ContractVersion         string            `json:"contractVersion"`
+	KeyID                   string            `json:"keyId"`
+	Payload                 []byte            `json:"payload"`
+	PayloadType             string            `json:"payloadType"`
+	SignatureEnvelopeType   string            `json:"signatureEnvelopeType"`
+	ExpiryDurationInSeconds uint64            `json:"expiryDurationInSeconds,omitempty"`
+	PluginConfig            map[string]string `json:"pluginConfig,omitempty"`
+}
+
+// GenerateEnvelopeResponse is the response of the generate-envelope command.
+type GenerateEnvelopeResponse struct {
+	SignatureEnvelope     []byte            `json:"signatureEnvelope"`
+	SignatureEnvelopeType string            `json:"signatureEnvelopeType"`
+	Annotations           map[string]string `json:"annotations,omitempty"`
+}
+
+// Validate validates the generate-envelope request.
+func (r *GenerateEnvelopeRequest) Validate() error {
+	if err := validateContractVersion(r.ContractVersion); err != nil {
+		return err
+	}
+	if r.KeyID == "" {
+		return invalidRequest(errors.New("missing key id"))
+	}
+	if r.SignatureEnvelopeType != MediaTypeEnvelope {
+		return invalidRequest(fmt.Errorf("signature envelope type %q is not supported", r.SignatureEnvelopeType))
+	}
+	if r.PayloadType != MediaTypePayload {
+		return invalidRequest(fmt.Errorf("payload type %q is not supported", r.PayloadType))
+	}
+	if len(r.Payload) == 0 {
+		return invalidRequest(errors.New("missing payload"))
+	}
+	return nil
+}
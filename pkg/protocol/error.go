@@ -0,0 +1,33 @@
+package protocol
+
+// ErrorCode is the code of the error response.
+type ErrorCode string
+
+// Error codes of the Notation plugin contract.
+const (
+	// ErrorCodeValidation indicates that the request is invalid.
+	ErrorCodeValidation ErrorCode = "VALIDATION_ERROR"
+
+	// ErrorCodeUnsupportedContractVersion indicates that the contract version
+	// of the request is not supported.
+	ErrorCodeUnsupportedContractVersion ErrorCode = "UNSUPPORTED_CONTRACT_VERSION"
+
+	// ErrorCodeAccessDenied indicates that the access to the key or the
+	// required resources is denied.
+	ErrorCodeAccessDenied ErrorCode = "ACCESS_DENIED"
+
+	// ErrorCodeGeneric indicates any other error.
+	ErrorCodeGeneric ErrorCode = "ERROR"
+)
+
+// Error is the error response written to the standard error.
+type Error struct {
+	Code     ErrorCode         `json:"errorCode"`
+	Message  string            `json:"errorMessage,omitempty"`
+	Metadata map[string]string `json:"errorMetadata,omitempty"`
+}
+
+// Error returns the error message.
+func (e *Error) Error() string {
+	return string(e.Code) + ": " + e.Message
+}
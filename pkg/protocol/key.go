@@ -0,0 +1,109 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+)
+
+// KeySpec is the type and the size of a signing key.
+type KeySpec string
+
+// Key specs of the Notation plugin contract.
+const (
+	KeySpecRSA2048 KeySpec = "RSA-2048"
+	KeySpecRSA3072 KeySpec = "RSA-3072"
+	KeySpecRSA4096 KeySpec = "RSA-4096"
+	KeySpecEC256   KeySpec = "EC-256"
+	KeySpecEC384   KeySpec = "EC-384"
+	KeySpecEC521   KeySpec = "EC-521"
+)
+
+// HashAlgorithm is the hash algorithm used to generate raw signatures.
+type HashAlgorithm string
+
+// Hash algorithms of the Notation plugin contract.
+const (
+	HashAlgorithmSHA256 HashAlgorithm = "SHA-256"
+	HashAlgorithmSHA384 HashAlgorithm = "SHA-384"
+	HashAlgorithmSHA512 HashAlgorithm = "SHA-512"
+)
+
+// SigningAlgorithm is the algorithm of raw signatures.
+type SigningAlgorithm string
+
+// Signing algorithms of the Notation plugin contract.
+const (
+	SigningAlgorithmRSASSAPSSSHA256 SigningAlgorithm = "RSASSA-PSS-SHA-256"
+	SigningAlgorithmRSASSAPSSSHA384 SigningAlgorithm = "RSASSA-PSS-SHA-384"
+	SigningAlgorithmRSASSAPSSSHA512 SigningAlgorithm = "RSASSA-PSS-SHA-512"
+	SigningAlgorithmECDSASHA256     SigningAlgorithm = "ECDSA-SHA-256"
+	SigningAlgorithmECDSASHA384     SigningAlgorithm = "ECDSA-SHA-384"
+	SigningAlgorithmECDSASHA512     SigningAlgorithm = "ECDSA-SHA-512"
+)
+
+// DescribeKeyRequest is the request of the describe-key command.
+type DescribeKeyRequest struct {
+	ContractVersion string            `json:"contractVersion"`
+	KeyID           string            `json:"keyId"`
+	PluginConfig    map[string]string `json:"pluginConfig,omitempty"`
+}
+
+// DescribeKeyResponse is the response of the describe-key command.
+type DescribeKeyResponse struct {
+	KeyID   string  `json:"keyId"`
+	KeySpec KeySpec `json:"keySpec"`
+}
+
+// GenerateSignatureRequest is the request of the generate-signature command.
+type GenerateSignatureRequest struct {
+	ContractVersion string            `json:"contractVersion"`
+	KeyID           string            `json:"keyId"`
+	KeySpec         KeySpec           `json:"keySpec"`
+	Hash            HashAlgorithm     `json:"hashAlgorithm"`
+	Payload         []byte            `json:"payload"`
+	PluginConfig    map[string]string `json:"pluginConfig,omitempty"`
+}
+
+// GenerateSignatureResponse is the response of the generate-signature
+// command.
+type GenerateSignatureResponse struct {
+	KeyID            string           `json:"keyId"`
+	Signature        []byte           `json:"signature"`
+	SigningAlgorithm SigningAlgorithm `json:"signingAlgorithm"`
+	CertificateChain [][]byte         `json:"certificateChain"`
+}
+
+// Validate validates the describe-key request.
+func (r *DescribeKeyRequest) Validate() error {
+	if err := validateContractVersion(r.ContractVersion); err != nil {
+		return err
+	}
+	if r.KeyID == "" {
+		return invalidRequest(errors.New("missing key id"))
+	}
+	return nil
+}
+
+// Validate validates the generate-signature request.
+func (r *GenerateSignatureRequest) Validate() error {
+	if err := validateContractVersion(r.ContractVersion); err != nil {
+		return err
+	}
+	if r.KeyID == "" {
+		return invalidRequest(errors.New("missing key id"))
+	}
+	switch r.KeySpec {
+	case KeySpecRSA2048, KeySpecRSA3072, KeySpecRSA4096, KeySpecEC256, KeySpecEC384, KeySpecEC521:
+	default:
+		return invalidRequest(fmt.Errorf("unknown key spec %q", r.KeySpec))
+	}
+	switch r.Hash {
+	case HashAlgorithmSHA256, HashAlgorithmSHA384, HashAlgorithmSHA512:
+	default:
+		return invalidRequest(fmt.Errorf("unknown hash algorithm %q", r.Hash))
+	}
+	if len(r.Payload) == 0 {
+		return invalidRequest(errors.New("missing payload"))
+	}
+	return nil
+}
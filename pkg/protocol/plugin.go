@@ -0,0 +1,57 @@
+package protocol
+
+import "fmt"
+
+// ContractVersion is the version of the Notation plugin contract implemented
+// by the plugin.
+const ContractVersion = "1.0"
+
+// Commands of the Notation plugin contract.
+const (
+	CommandGetMetadata       = "get-plugin-metadata"
+	CommandDescribeKey       = "describe-key"
+	CommandGenerateSignature = "generate-signature"
+	CommandGenerateEnvelope  = "generate-envelope"
+	CommandVerifySignature   = "verify-signature"
+)
+
+// Capability is a feature supported by the plugin.
+type Capability string
+
+// Capabilities of the Notation plugin contract.
+const (
+	// CapabilitySignatureGenerator generates raw signatures.
+	CapabilitySignatureGenerator Capability = "SIGNATURE_GENERATOR.RAW"
+
+	// CapabilityEnvelopeGenerator generates signature envelopes.
+	CapabilityEnvelopeGenerator Capability = "SIGNATURE_GENERATOR.ENVELOPE"
+
+	// CapabilityTrustedIdentityVerifier verifies the signer against the
+	// trusted identities.
+	CapabilityTrustedIdentityVerifier Capability = "SIGNATURE_VERIFIER.TRUSTED_IDENTITY"
+
+	// CapabilityRevocationCheckVerifier checks the revocation status of the
+	// certificate chain.
+	CapabilityRevocationCheckVerifier Capability = "SIGNATURE_VERIFIER.REVOCATION_CHECK"
+)
+
+// GetMetadataResponse is the response of the get-plugin-metadata command.
+type GetMetadataResponse struct {
+	Name                      string       `json:"name"`
+	Description               string       `json:"description"`
+	Version                   string       `json:"version"`
+	URL                       string       `json:"url"`
+	SupportedContractVersions []string     `json:"supportedContractVersions"`
+	Capabilities              []Capability `json:"capabilities"`
+}
+
+// validateContractVersion checks if the contract version is supported.
+func validateContractVersion(version string) error {
+	if version != ContractVersion {
+		return &Error{
+			Code:    ErrorCodeUnsupportedContractVersion,
+			Message: fmt.Sprintf("contract version %q is not supported", version),
+		}
+	}
+	return nil
+}
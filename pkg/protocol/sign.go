@@ -1,11 +1,71 @@
 package protocol
 
-import "github.com/notaryproject/notation-go"
+import (
+	"errors"
+	"time"
+
+	"github.com/notaryproject/notation-go"
+)
 
 // SignRequest is the request to sign artifacts
 type SignRequest struct {
-	Version     string               `json:"version"`
-	Descriptor  notation.Descriptor  `json:"descriptor"`
+	Version    string              `json:"version"`
+	Descriptor notation.Descriptor `json:"descriptor"`
+
+	// SignOptions contains the sign options, of which only the expiry is
+	// transferred since the timestamping options cannot be encoded in JSON.
 	SignOptions notation.SignOptions `json:"signOptions"`
-	KMSProfile  KMSProfileSuite      `json:"kmsProfile"`
+
+	KMSProfile KMSProfileSuite `json:"kmsProfile"`
+}
+
+// Validate validates the sign request of the current version.
+func (r *SignRequest) Validate() error {
+	if r.Version != Version {
+		return unsupportedVersion(r.Version)
+	}
+	if err := validateDescriptor(r.Descriptor); err != nil {
+		return err
+	}
+	if r.KMSProfile.ID == "" {
+		return invalidRequest(errors.New("missing key id"))
+	}
+	return nil
+}
+
+// validateDescriptor validates the descriptor of the artifact.
+func validateDescriptor(desc notation.Descriptor) error {
+	if desc.MediaType == "" {
+		return invalidRequest(errors.New("missing media type"))
+	}
+	if err := desc.Digest.Validate(); err != nil {
+		return invalidRequest(err)
+	}
+	if desc.Size < 0 {
+		return invalidRequest(errors.New("negative size"))
+	}
+	return nil
+}
+
+// legacySignRequest is the sign request of the legacy version, whose sign
+// options are encoded from `notation.SignOptions` without JSON tags.
+type legacySignRequest struct {
+	Descriptor  notation.Descriptor `json:"descriptor"`
+	SignOptions struct {
+		Expiry time.Time `json:"Expiry"`
+	} `json:"signOptions"`
+	KMSProfile KMSProfileSuite `json:"kmsProfile"`
+}
+
+// upgrade upgrades the request to the current version.
+// The timestamping options are dropped since they cannot be encoded in JSON.
+func (r *legacySignRequest) upgrade() *SignRequest {
+	return &SignRequest{
+		Version:    Version,
+		Descriptor: r.Descriptor,
+		SignOptions: notation.SignOptions{
+			Expiry: r.SignOptions.Expiry,
+		},
+		KMSProfile: r.KMSProfile,
+	}
 }
@@ -0,0 +1,69 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// VerifySignatureRequest is the request of the verify-signature command.
+// The signature envelope is verified by the Notation host, and the plugin
+// performs the verification capabilities requested by the trust policy.
+type VerifySignatureRequest struct {
+	ContractVersion string            `json:"contractVersion"`
+	Signature       Signature         `json:"signature"`
+	TrustPolicy     TrustPolicy       `json:"trustPolicy"`
+	PluginConfig    map[string]string `json:"pluginConfig,omitempty"`
+}
+
+// Signature contains the information of the verified signature envelope.
+type Signature struct {
+	CriticalAttributes    CriticalAttributes `json:"criticalAttributes"`
+	UnprocessedAttributes []string           `json:"unprocessedAttributes"`
+	CertificateChain      [][]byte           `json:"certificateChain"`
+}
+
+// CriticalAttributes contains the critical attributes of the signature.
+type CriticalAttributes struct {
+	ContentType          string                 `json:"contentType"`
+	SigningScheme        string                 `json:"signingScheme"`
+	Expiry               *time.Time             `json:"expiry,omitempty"`
+	AuthenticSigningTime *time.Time             `json:"authenticSigningTime,omitempty"`
+	ExtendedAttributes   map[string]interface{} `json:"extendedAttributes,omitempty"`
+}
+
+// TrustPolicy contains the trust policy applied to the signature.
+type TrustPolicy struct {
+	TrustedIdentities     []string     `json:"trustedIdentities"`
+	SignatureVerification []Capability `json:"signatureVerification"`
+}
+
+// VerifySignatureResponse is the response of the verify-signature command.
+type VerifySignatureResponse struct {
+	VerificationResults map[Capability]*VerificationResult `json:"verificationResults"`
+	ProcessedAttributes []interface{}                      `json:"processedAttributes"`
+}
+
+// VerificationResult is the result of a verification capability.
+type VerificationResult struct {
+	Success bool   `json:"success"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Validate validates the verify-signature request.
+func (r *VerifySignatureRequest) Validate() error {
+	if err := validateContractVersion(r.ContractVersion); err != nil {
+		return err
+	}
+	if len(r.Signature.CertificateChain) == 0 {
+		return invalidRequest(errors.New("missing certificate chain"))
+	}
+	for _, capability := range r.TrustPolicy.SignatureVerification {
+		switch capability {
+		case CapabilityTrustedIdentityVerifier, CapabilityRevocationCheckVerifier:
+		default:
+			return invalidRequest(fmt.Errorf("verification capability %q is not supported", capability))
+		}
+	}
+	return nil
+}
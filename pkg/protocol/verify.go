@@ -1,6 +1,10 @@
 package protocol
 
-import "github.com/notaryproject/notation-go"
+import (
+	"errors"
+
+	"github.com/notaryproject/notation-go"
+)
 
 // VerifyRequest is the request to verify a signature.
 type VerifyRequest struct {
@@ -9,3 +13,29 @@ type VerifyRequest struct {
 	VerifyOptions notation.VerifyOptions `json:"verifyOptions"`
 	KMSProfile    KMSProfileSuite        `json:"kmsProfile"`
 }
+
+// Validate validates the verify request of the current version.
+func (r *VerifyRequest) Validate() error {
+	if r.Version != Version {
+		return unsupportedVersion(r.Version)
+	}
+	if len(r.Signature) == 0 {
+		return invalidRequest(errors.New("missing signature"))
+	}
+	return nil
+}
+
+// legacyVerifyRequest is the verify request of the legacy version.
+type legacyVerifyRequest struct {
+	Signature  []byte          `json:"signature"`
+	KMSProfile KMSProfileSuite `json:"kmsProfile"`
+}
+
+// upgrade upgrades the request to the current version.
+func (r *legacyVerifyRequest) upgrade() *VerifyRequest {
+	return &VerifyRequest{
+		Version:    Version,
+		Signature:  r.Signature,
+		KMSProfile: r.KMSProfile,
+	}
+}
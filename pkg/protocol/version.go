@@ -0,0 +1,114 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Versions of the sign and verify requests.
+const (
+	// VersionLegacy is the version of the requests sent by the notation
+	// prototype of the feat-kv-extensibility branch. Requests without
+	// version are treated as legacy requests.
+	VersionLegacy = "0.1"
+
+	// Version is the current version of the requests.
+	Version = "1.0"
+)
+
+// SupportedVersions lists the supported versions of the sign and verify
+// requests.
+var SupportedVersions = []string{VersionLegacy, Version}
+
+// DecodeSignRequest decodes the sign request from the reader.
+// Requests of the current version are decoded strictly, and unknown fields
+// are rejected. Requests of the legacy version are upgraded to the current
+// version.
+func DecodeSignRequest(r io.Reader) (*SignRequest, error) {
+	raw, version, err := decodeVersion(r)
+	if err != nil {
+		return nil, err
+	}
+	switch version {
+	case Version:
+		var req SignRequest
+		if err := decodeStrict(raw, &req); err != nil {
+			return nil, err
+		}
+		return &req, nil
+	case VersionLegacy, "":
+		var req legacySignRequest
+		if err := json.Unmarshal(raw, &req); err != nil {
+			return nil, invalidRequest(err)
+		}
+		return req.upgrade(), nil
+	}
+	return nil, unsupportedVersion(version)
+}
+
+// DecodeVerifyRequest decodes the verify request from the reader.
+// Requests of the current version are decoded strictly, and unknown fields
+// are rejected. Requests of the legacy version are upgraded to the current
+// version.
+func DecodeVerifyRequest(r io.Reader) (*VerifyRequest, error) {
+	raw, version, err := decodeVersion(r)
+	if err != nil {
+		return nil, err
+	}
+	switch version {
+	case Version:
+		var req VerifyRequest
+		if err := decodeStrict(raw, &req); err != nil {
+			return nil, err
+		}
+		return &req, nil
+	case VersionLegacy, "":
+		var req legacyVerifyRequest
+		if err := json.Unmarshal(raw, &req); err != nil {
+			return nil, invalidRequest(err)
+		}
+		return req.upgrade(), nil
+	}
+	return nil, unsupportedVersion(version)
+}
+
+// decodeVersion reads a request from the reader, and peeks its version.
+func decodeVersion(r io.Reader) (json.RawMessage, string, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, "", invalidRequest(err)
+	}
+	var header struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return nil, "", invalidRequest(err)
+	}
+	return raw, header.Version, nil
+}
+
+// decodeStrict decodes the request, and rejects unknown fields.
+func decodeStrict(raw json.RawMessage, req interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(req); err != nil {
+		return invalidRequest(err)
+	}
+	return nil
+}
+
+func invalidRequest(err error) error {
+	return &Error{
+		Code:    ErrorCodeValidation,
+		Message: fmt.Sprintf("invalid request: %v", err),
+	}
+}
+
+func unsupportedVersion(version string) error {
+	return &Error{
+		Code:    ErrorCodeUnsupportedContractVersion,
+		Message: fmt.Sprintf("request version %q is not supported: supported versions are %q", version, SupportedVersions),
+	}
+}
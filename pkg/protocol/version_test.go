@@ -0,0 +1,77 @@
+package protocol
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testDigest = "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+func TestDecodeSignRequest(t *testing.T) {
+	expiry := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		request string
+	}{
+		{
+			name:    "current version",
+			request: `{"version":"1.0","descriptor":{"mediaType":"test","digest":"` + testDigest + `","size":5},"signOptions":{"expiry":"2022-05-01T00:00:00Z"},"kmsProfile":{"id":"key"}}`,
+		},
+		{
+			name:    "current version with encoded sign options",
+			request: `{"version":"1.0","descriptor":{"mediaType":"test","digest":"` + testDigest + `","size":5},"signOptions":{"Expiry":"2022-05-01T00:00:00Z","TSA":null,"TSAVerifyOptions":{"DNSName":""}},"kmsProfile":{"id":"key"}}`,
+		},
+		{
+			name:    "legacy version",
+			request: `{"version":"0.1","descriptor":{"mediaType":"test","digest":"` + testDigest + `","size":5},"signOptions":{"Expiry":"2022-05-01T00:00:00Z","TSA":null,"TSAVerifyOptions":{"DNSName":""}},"kmsProfile":{"id":"key"}}`,
+		},
+		{
+			name:    "missing version",
+			request: `{"descriptor":{"mediaType":"test","digest":"` + testDigest + `","size":5},"signOptions":{"Expiry":"2022-05-01T00:00:00Z"},"kmsProfile":{"id":"key"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := DecodeSignRequest(strings.NewReader(tt.request))
+			if err != nil {
+				t.Fatalf("DecodeSignRequest() error = %v", err)
+			}
+			if err := req.Validate(); err != nil {
+				t.Fatalf("SignRequest.Validate() error = %v", err)
+			}
+			if req.Version != Version {
+				t.Errorf("SignRequest.Version = %v, want %v", req.Version, Version)
+			}
+			if !req.SignOptions.Expiry.Equal(expiry) {
+				t.Errorf("SignRequest.SignOptions.Expiry = %v, want %v", req.SignOptions.Expiry, expiry)
+			}
+		})
+	}
+}
+
+func TestDecodeRequestErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		request string
+		want    ErrorCode
+	}{
+		{"malformed", `{"version":`, ErrorCodeValidation},
+		{"unsupported version", `{"version":"2.0"}`, ErrorCodeUnsupportedContractVersion},
+		{"unknown field", `{"version":"1.0","signature":"AA==","unknown":true}`, ErrorCodeValidation},
+		{"missing signature", `{"version":"1.0"}`, ErrorCodeValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := DecodeVerifyRequest(strings.NewReader(tt.request))
+			if err == nil {
+				err = req.Validate()
+			}
+			var got *Error
+			if !errors.As(err, &got) || got.Code != tt.want {
+				t.Errorf("DecodeVerifyRequest() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
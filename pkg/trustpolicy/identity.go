@@ -0,0 +1,262 @@
+package trustpolicy
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Identity types of the trusted identities.
+const (
+	// IdentitySubject matches the full subject DN in the string representation
+	// of RFC 4514, for example, `x509.subject: C=US, O=Example\, Inc., CN=signer`.
+	IdentitySubject = "x509.subject"
+
+	// IdentitySANDNS matches a DNS name in the SAN with a pattern, for example,
+	// `x509.san.dns: *.example.com`.
+	IdentitySANDNS = "x509.san.dns"
+
+	// IdentitySANURI matches a URI in the SAN with a pattern, for example,
+	// `x509.san.uri: https://example.com/*`.
+	IdentitySANURI = "x509.san.uri"
+
+	// IdentitySANEmail matches an email address in the SAN with a pattern,
+	// for example, `x509.san.email: *@example.com`.
+	IdentitySANEmail = "x509.san.email"
+)
+
+// dnAttributes maps the supported DN attribute types to their OIDs.
+var dnAttributes = map[string]asn1.ObjectIdentifier{
+	"C":            {2, 5, 4, 6},
+	"ST":           {2, 5, 4, 8},
+	"L":            {2, 5, 4, 7},
+	"STREET":       {2, 5, 4, 9},
+	"POSTALCODE":   {2, 5, 4, 17},
+	"O":            {2, 5, 4, 10},
+	"OU":           {2, 5, 4, 11},
+	"CN":           {2, 5, 4, 3},
+	"SERIALNUMBER": {2, 5, 4, 5},
+}
+
+// Identity is a parsed trusted identity.
+type Identity struct {
+	// Type is the identity type.
+	Type string
+
+	// Value is the subject DN or the SAN pattern.
+	Value string
+
+	// attributes contains the parsed subject DN attributes.
+	attributes [][2]string
+}
+
+// ParseIdentity parses a trusted identity in the format of `<type>: <value>`.
+// Patterns of the SAN identities use the syntax of `path.Match`, and are
+// matched label by label for DNS names.
+func ParseIdentity(value string) (*Identity, error) {
+	typ, val, ok := strings.Cut(value, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid trusted identity: %q", value)
+	}
+	identity := &Identity{
+		Type:  strings.TrimSpace(typ),
+		Value: strings.TrimSpace(val),
+	}
+	if identity.Value == "" {
+		return nil, fmt.Errorf("invalid trusted identity: %q: empty value", value)
+	}
+	switch identity.Type {
+	case IdentitySubject:
+		attributes, err := parseDN(identity.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted identity: %q: %w", value, err)
+		}
+		identity.attributes = attributes
+	case IdentitySANDNS, IdentitySANURI, IdentitySANEmail:
+		if _, err := path.Match(identity.Value, ""); err != nil {
+			return nil, fmt.Errorf("invalid trusted identity: %q: %w", value, err)
+		}
+	default:
+		return nil, fmt.Errorf("invalid trusted identity: %q: unknown type %q", value, identity.Type)
+	}
+	return identity, nil
+}
+
+// parseDN parses a DN in the string representation of RFC 4514, such as
+// `C=US, O=Example\, Inc., CN=signer`. Quoted values are accepted, and the
+// attributes of multi-valued RDNs joined by `+` are flattened.
+func parseDN(dn string) ([][2]string, error) {
+	var attributes [][2]string
+	for i := 0; ; {
+		j := strings.IndexByte(dn[i:], '=')
+		if j < 0 {
+			return nil, fmt.Errorf("invalid attribute: %q", strings.TrimSpace(dn[i:]))
+		}
+		typ := strings.ToUpper(strings.TrimSpace(dn[i : i+j]))
+		if _, ok := dnAttributes[typ]; !ok {
+			return nil, fmt.Errorf("unsupported attribute type: %q", typ)
+		}
+		i += j + 1
+		for i < len(dn) && dn[i] == ' ' {
+			i++
+		}
+		value, next, err := parseDNValue(dn, i)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attribute %q: %w", typ, err)
+		}
+		if value == "" {
+			return nil, fmt.Errorf("invalid attribute %q: empty value", typ)
+		}
+		attributes = append(attributes, [2]string{typ, value})
+		if next >= len(dn) {
+			return attributes, nil
+		}
+		i = next + 1
+	}
+}
+
+// parseDNValue parses the attribute value starting at the index, and returns
+// the unescaped value and the index of the following separator.
+func parseDNValue(dn string, i int) (string, int, error) {
+	var b strings.Builder
+	if i < len(dn) && dn[i] == '"' {
+		for i++; i < len(dn) && dn[i] != '"'; i++ {
+			if dn[i] == '\\' {
+				if i++; i == len(dn) {
+					break
+				}
+			}
+			b.WriteByte(dn[i])
+		}
+		if i >= len(dn) {
+			return "", 0, errors.New("unterminated quoted value")
+		}
+		for i++; i < len(dn) && dn[i] == ' '; i++ {
+		}
+		if i < len(dn) && dn[i] != ',' && dn[i] != '+' {
+			return "", 0, fmt.Errorf("unexpected character %q after quoted value", dn[i])
+		}
+		return b.String(), i, nil
+	}
+
+	// unescaped trailing spaces are not part of the value
+	end := 0
+	for ; i < len(dn); i++ {
+		switch c := dn[i]; c {
+		case ',', '+':
+			return b.String()[:end], i, nil
+		case '\\':
+			if i+2 < len(dn) && isHex(dn[i+1]) && isHex(dn[i+2]) {
+				v, _ := strconv.ParseUint(dn[i+1:i+3], 16, 8)
+				b.WriteByte(byte(v))
+				i += 2
+			} else if i+1 < len(dn) && strings.IndexByte(dnSpecialCharacters, dn[i+1]) >= 0 {
+				b.WriteByte(dn[i+1])
+				i++
+			} else {
+				return "", 0, errors.New("invalid escape sequence")
+			}
+			end = b.Len()
+		case '"', ';', '<', '>':
+			return "", 0, fmt.Errorf("unescaped character %q", c)
+		default:
+			b.WriteByte(c)
+			if c != ' ' {
+				end = b.Len()
+			}
+		}
+	}
+	return b.String()[:end], i, nil
+}
+
+// dnSpecialCharacters lists the characters escaped by a backslash in the
+// attribute values of a DN.
+// Reference: RFC 4514 3. Parsing a String Back to a Distinguished Name.
+const dnSpecialCharacters = " \"#+,;<=>\\"
+
+// isHex reports whether the character is a hexadecimal digit.
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// Match reports whether the certificate matches the identity.
+// A subject identity matches if the subject DN of the certificate consists of
+// exactly its attributes, regardless of their order.
+func (i *Identity) Match(cert *x509.Certificate) bool {
+	switch i.Type {
+	case IdentitySubject:
+		return matchDN(i.attributes, cert.Subject)
+	case IdentitySANDNS:
+		for _, name := range cert.DNSNames {
+			if matchDNS(i.Value, name) {
+				return true
+			}
+		}
+	case IdentitySANURI:
+		for _, uri := range cert.URIs {
+			if ok, _ := path.Match(i.Value, uri.String()); ok {
+				return true
+			}
+		}
+	case IdentitySANEmail:
+		for _, email := range cert.EmailAddresses {
+			if ok, _ := path.Match(i.Value, email); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchDNS matches the DNS name with the pattern label by label.
+func matchDNS(pattern, name string) bool {
+	patternLabels := strings.Split(strings.ToLower(pattern), ".")
+	nameLabels := strings.Split(strings.ToLower(name), ".")
+	if len(patternLabels) != len(nameLabels) {
+		return false
+	}
+	for i, label := range patternLabels {
+		if ok, _ := path.Match(label, nameLabels[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// matchDN reports whether the DN consists of exactly the attributes. DNs with
+// attributes of unsupported types never match.
+func matchDN(attributes [][2]string, name pkix.Name) bool {
+	names := name.Names
+	if len(names) == 0 {
+		for _, rdn := range name.ToRDNSequence() {
+			names = append(names, rdn...)
+		}
+	}
+	if len(names) != len(attributes) {
+		return false
+	}
+	remaining := append([][2]string(nil), attributes...)
+	for _, atv := range names {
+		value, ok := atv.Value.(string)
+		if !ok {
+			return false
+		}
+		found := false
+		for k, attribute := range remaining {
+			if atv.Type.Equal(dnAttributes[attribute[0]]) && value == attribute[1] {
+				remaining = append(remaining[:k], remaining[k+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
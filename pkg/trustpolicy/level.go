@@ -0,0 +1,64 @@
+package trustpolicy
+
+// Level is the signature verification level.
+type Level string
+
+// Signature verification levels.
+const (
+	// LevelStrict enforces all the checks.
+	LevelStrict Level = "strict"
+
+	// LevelPermissive enforces the integrity, the authenticity, and the
+	// authentic timestamp checks, and logs the expiry and the revocation
+	// failures.
+	LevelPermissive Level = "permissive"
+
+	// LevelAudit enforces the integrity check only, and logs other failures.
+	LevelAudit Level = "audit"
+)
+
+// Check is a verification check governed by the verification level.
+type Check int
+
+// Verification checks.
+const (
+	// CheckIntegrity verifies the signature is not tampered.
+	CheckIntegrity Check = iota
+
+	// CheckAuthenticity verifies the signer chains to the trust stores and is
+	// a trusted identity.
+	CheckAuthenticity
+
+	// CheckAuthenticTimestamp verifies the signing certificate is valid at the
+	// signing time or at the time asserted by the timestamp.
+	CheckAuthenticTimestamp
+
+	// CheckExpiry verifies the signature is not expired.
+	CheckExpiry
+
+	// CheckRevocation verifies the certificates are not revoked.
+	CheckRevocation
+)
+
+// Enforces reports whether the failure of the check fails the verification
+// at the level.
+// Otherwise, the failure is logged.
+func (l Level) Enforces(check Check) bool {
+	switch l {
+	case LevelPermissive:
+		return check != CheckExpiry && check != CheckRevocation
+	case LevelAudit:
+		return check == CheckIntegrity
+	default:
+		return true
+	}
+}
+
+// valid reports whether the level is known.
+func (l Level) valid() bool {
+	switch l {
+	case LevelStrict, LevelPermissive, LevelAudit:
+		return true
+	}
+	return false
+}
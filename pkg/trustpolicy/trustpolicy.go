@@ -0,0 +1,195 @@
+// Package trustpolicy maps artifact scopes to trust stores, trusted signer
+// identities, and verification levels.
+package trustpolicy
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Version is the supported version of the trust policy document.
+const Version = "1.0"
+
+// Wildcard matches any registry scope or any signer identity.
+const Wildcard = "*"
+
+// Document is a trust policy document.
+type Document struct {
+	// Version is the version of the trust policy document.
+	Version string `json:"version"`
+
+	// TrustPolicies contains the trust policies.
+	TrustPolicies []Policy `json:"trustPolicies"`
+}
+
+// Policy is a trust policy for a set of artifact scopes.
+type Policy struct {
+	// Name is the unique name of the trust policy.
+	Name string `json:"name"`
+
+	// RegistryScopes lists the repositories in the format of
+	// `<registry>/<repository>` to which the policy applies, or the wildcard
+	// `*` for the global policy.
+	RegistryScopes []string `json:"registryScopes"`
+
+	// SignatureVerification is the verification level.
+	SignatureVerification Level `json:"signatureVerification"`
+
+	// TrustStores lists the names of the trust stores containing the trusted
+	// roots.
+	TrustStores []string `json:"trustStores"`
+
+	// TrustedIdentities lists the identities of the trusted signers, or the
+	// wildcard `*` to trust any signer chaining to the trust stores.
+	// See `ParseIdentity` for the format.
+	TrustedIdentities []string `json:"trustedIdentities"`
+}
+
+// Load reads and validates the trust policy document at the path.
+func Load(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid trust policy document: %w", err)
+	}
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Validate validates the trust policy document.
+func (d *Document) Validate() error {
+	if d.Version != Version {
+		return fmt.Errorf("trust policy: unsupported version: %q", d.Version)
+	}
+	if len(d.TrustPolicies) == 0 {
+		return errors.New("trust policy: no policy found")
+	}
+	names := make(map[string]struct{})
+	scopes := make(map[string]string)
+	for _, policy := range d.TrustPolicies {
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+		if _, ok := names[policy.Name]; ok {
+			return fmt.Errorf("trust policy %q: duplicated name", policy.Name)
+		}
+		names[policy.Name] = struct{}{}
+		for _, scope := range policy.RegistryScopes {
+			if name, ok := scopes[scope]; ok {
+				return fmt.Errorf("trust policy %q: registry scope %q is already used by %q", policy.Name, scope, name)
+			}
+			scopes[scope] = policy.Name
+		}
+	}
+	return nil
+}
+
+// Validate validates the trust policy.
+func (p *Policy) Validate() error {
+	if p.Name == "" {
+		return errors.New("trust policy: missing name")
+	}
+	if err := validateWildcard(p.RegistryScopes); err != nil {
+		return fmt.Errorf("trust policy %q: registry scopes: %w", p.Name, err)
+	}
+	for _, scope := range p.RegistryScopes {
+		if scope != Wildcard && !strings.Contains(scope, "/") {
+			return fmt.Errorf("trust policy %q: invalid registry scope: %q", p.Name, scope)
+		}
+	}
+	if !p.SignatureVerification.valid() {
+		return fmt.Errorf("trust policy %q: unknown signature verification level: %q", p.Name, p.SignatureVerification)
+	}
+	if len(p.TrustStores) == 0 {
+		return fmt.Errorf("trust policy %q: no trust store", p.Name)
+	}
+	if err := validateWildcard(p.TrustedIdentities); err != nil {
+		return fmt.Errorf("trust policy %q: trusted identities: %w", p.Name, err)
+	}
+	for _, identity := range p.TrustedIdentities {
+		if identity == Wildcard {
+			continue
+		}
+		if _, err := ParseIdentity(identity); err != nil {
+			return fmt.Errorf("trust policy %q: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateWildcard ensures the values are not empty, and the wildcard is not
+// mixed with other values.
+func validateWildcard(values []string) error {
+	if len(values) == 0 {
+		return errors.New("empty list")
+	}
+	for _, value := range values {
+		if value == Wildcard && len(values) > 1 {
+			return errors.New("wildcard must be the only value")
+		}
+	}
+	return nil
+}
+
+// Match returns the trust policy applicable to the artifact reference in the
+// format of `<registry>/<repository>[:<tag>|@<digest>]`.
+// The policy with the matching registry scope is preferred over the global
+// policy. An empty reference matches no policy.
+func (d *Document) Match(reference string) (*Policy, error) {
+	if reference == "" {
+		return nil, errors.New("trust policy: missing artifact reference")
+	}
+	scope := repositoryOf(reference)
+	var global *Policy
+	for i, policy := range d.TrustPolicies {
+		for _, s := range policy.RegistryScopes {
+			if s == Wildcard {
+				global = &d.TrustPolicies[i]
+			} else if s == scope {
+				return &d.TrustPolicies[i], nil
+			}
+		}
+	}
+	if global == nil {
+		return nil, fmt.Errorf("trust policy: no policy applicable to %q", reference)
+	}
+	return global, nil
+}
+
+// repositoryOf strips the tag or the digest from the artifact reference.
+func repositoryOf(reference string) string {
+	if i := strings.Index(reference, "@"); i >= 0 {
+		return reference[:i]
+	}
+	if i := strings.LastIndex(reference, ":"); i > strings.LastIndex(reference, "/") {
+		return reference[:i]
+	}
+	return reference
+}
+
+// VerifyIdentity verifies the signing certificate against the trusted
+// identities of the policy.
+func (p *Policy) VerifyIdentity(cert *x509.Certificate) error {
+	for _, value := range p.TrustedIdentities {
+		if value == Wildcard {
+			return nil
+		}
+		identity, err := ParseIdentity(value)
+		if err != nil {
+			return err
+		}
+		if identity.Match(cert) {
+			return nil
+		}
+	}
+	return fmt.Errorf("trust policy %q: signer %q is not a trusted identity", p.Name, cert.Subject)
+}
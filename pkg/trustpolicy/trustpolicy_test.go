@@ -0,0 +1,206 @@
+package trustpolicy
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trustpolicy.json")
+	data := []byte(`{
+		"version": "1.0",
+		"trustPolicies": [
+			{
+				"name": "wabbit-networks",
+				"registryScopes": ["registry.wabbit-networks.io/software/net-monitor"],
+				"signatureVerification": "strict",
+				"trustStores": ["wabbit-networks"],
+				"trustedIdentities": ["x509.subject: C=US, ST=WA, O=wabbit-networks.io"]
+			},
+			{
+				"name": "global",
+				"registryScopes": ["*"],
+				"signatureVerification": "audit",
+				"trustStores": ["global"],
+				"trustedIdentities": ["*"]
+			}
+		]
+	}`)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	doc, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		reference string
+		want      string
+	}{
+		{"registry.wabbit-networks.io/software/net-monitor:v1", "wabbit-networks"},
+		{"registry.wabbit-networks.io/software/net-monitor@sha256:0123", "wabbit-networks"},
+		{"localhost:5000/software/net-monitor", "global"},
+	}
+	for _, tt := range tests {
+		policy, err := doc.Match(tt.reference)
+		if err != nil {
+			t.Fatalf("Match(%q) error = %v", tt.reference, err)
+		}
+		if policy.Name != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.reference, policy.Name, tt.want)
+		}
+	}
+	if _, err := doc.Match(""); err == nil {
+		t.Error("Match() error = nil, want error for missing reference")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := Policy{
+		Name:                  "test",
+		RegistryScopes:        []string{"test.registry.io/test"},
+		SignatureVerification: LevelStrict,
+		TrustStores:           []string{"test"},
+		TrustedIdentities:     []string{"*"},
+	}
+	tests := []struct {
+		name   string
+		modify func(doc *Document)
+	}{
+		{"unsupported version", func(doc *Document) { doc.Version = "2.0" }},
+		{"missing name", func(doc *Document) { doc.TrustPolicies[0].Name = "" }},
+		{"duplicated name", func(doc *Document) {
+			policy := valid
+			policy.RegistryScopes = []string{"*"}
+			doc.TrustPolicies = append(doc.TrustPolicies, policy)
+		}},
+		{"duplicated scope", func(doc *Document) {
+			policy := valid
+			policy.Name = "other"
+			doc.TrustPolicies = append(doc.TrustPolicies, policy)
+		}},
+		{"mixed wildcard scope", func(doc *Document) {
+			doc.TrustPolicies[0].RegistryScopes = []string{"*", "test.registry.io/other"}
+		}},
+		{"invalid scope", func(doc *Document) { doc.TrustPolicies[0].RegistryScopes = []string{"test"} }},
+		{"unknown level", func(doc *Document) { doc.TrustPolicies[0].SignatureVerification = "skip" }},
+		{"missing trust store", func(doc *Document) { doc.TrustPolicies[0].TrustStores = nil }},
+		{"missing identity", func(doc *Document) { doc.TrustPolicies[0].TrustedIdentities = nil }},
+		{"unknown identity type", func(doc *Document) {
+			doc.TrustPolicies[0].TrustedIdentities = []string{"x509.issuer: CN=test"}
+		}},
+		{"invalid subject", func(doc *Document) {
+			doc.TrustPolicies[0].TrustedIdentities = []string{"x509.subject: CN"}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &Document{
+				Version:       Version,
+				TrustPolicies: []Policy{valid},
+			}
+			if err := doc.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			tt.modify(doc)
+			if err := doc.Validate(); err == nil {
+				t.Error("Validate() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestVerifyIdentityExactSubject(t *testing.T) {
+	policy := &Policy{
+		Name:              "test",
+		TrustedIdentities: []string{"x509.subject: CN=signer"},
+	}
+	cert := &x509.Certificate{
+		Subject: pkix.Name{
+			Organization: []string{"Attacker"},
+			CommonName:   "signer",
+		},
+	}
+	if err := policy.VerifyIdentity(cert); err == nil {
+		t.Error("VerifyIdentity() with extra subject attributes error = nil, want error")
+	}
+
+	policy.TrustedIdentities = []string{`x509.subject: O=Example\, Inc., CN=signer`}
+	cert.Subject.Organization = []string{"Example, Inc."}
+	if err := policy.VerifyIdentity(cert); err != nil {
+		t.Errorf("VerifyIdentity() with escaped subject error = %v", err)
+	}
+	cert.Subject.Organization = []string{"Example", "Inc."}
+	if err := policy.VerifyIdentity(cert); err == nil {
+		t.Error("VerifyIdentity() with split subject error = nil, want error")
+	}
+}
+
+func TestParseDNErrors(t *testing.T) {
+	for _, dn := range []string{
+		"CN",
+		"CN=",
+		"CN=a, EMAIL=b",
+		`CN=a\`,
+		`CN=a\x`,
+		`CN="a`,
+		`CN="a"b`,
+		"CN=a;b",
+	} {
+		if _, err := parseDN(dn); err == nil {
+			t.Errorf("parseDN(%q) error = nil, want error", dn)
+		}
+	}
+}
+
+func TestVerifyIdentity(t *testing.T) {
+	uri, err := url.Parse("https://signer.example.com/builds/1")
+	if err != nil {
+		t.Fatalf("url.Parse() error = %v", err)
+	}
+	cert := &x509.Certificate{
+		Subject: pkix.Name{
+			Country:      []string{"US"},
+			Province:     []string{"WA"},
+			Organization: []string{"wabbit-networks.io"},
+			CommonName:   "signer",
+		},
+		DNSNames:       []string{"signer.example.com"},
+		EmailAddresses: []string{"signer@example.com"},
+		URIs:           []*url.URL{uri},
+	}
+	tests := []struct {
+		identity string
+		want     bool
+	}{
+		{"*", true},
+		{"x509.subject: C=US, ST=WA, O=wabbit-networks.io, CN=signer", true},
+		{"x509.subject: cn=signer, o=wabbit-networks.io, st=WA, c=US", true},
+		{"x509.subject: C=US, ST=WA, O=\"wabbit-networks.io\", CN=signer", true},
+		{"x509.subject: C=US, ST=WA, O=wabbit\\2dnetworks.io, CN=signer", true},
+		{"x509.subject: o=wabbit-networks.io", false},
+		{"x509.subject: C=US, ST=WA, O=wabbit-networks.io, CN=signer, CN=signer", false},
+		{"x509.subject: C=US, O=acme-rockets.io", false},
+		{"x509.san.dns: *.example.com", true},
+		{"x509.san.dns: *.com", false},
+		{"x509.san.uri: https://signer.example.com/builds/*", true},
+		{"x509.san.uri: https://example.com/*", false},
+		{"x509.san.email: *@example.com", true},
+		{"x509.san.email: *@example.org", false},
+	}
+	for _, tt := range tests {
+		policy := &Policy{
+			Name:              "test",
+			TrustedIdentities: []string{tt.identity},
+		}
+		err := policy.VerifyIdentity(cert)
+		if got := err == nil; got != tt.want {
+			t.Errorf("VerifyIdentity() with %q error = %v, want match %v", tt.identity, err, tt.want)
+		}
+	}
+}